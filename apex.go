@@ -1,6 +1,8 @@
 package gateway
 
 import (
+	"strings"
+
 	"github.com/coredns/coredns/plugin/pkg/dnsutil"
 	"github.com/coredns/coredns/request"
 
@@ -69,7 +71,7 @@ func (gw *Gateway) soa(state request.Request) *dns.SOA {
 	soa := &dns.SOA{Hdr: header,
 		Mbox:    dnsutil.Join(gw.hostmaster, gw.apex, state.Zone),
 		Ns:      dnsutil.Join(gw.apex, state.Zone),
-		Serial:  12345, // Also dynamic?
+		Serial:  gw.zoneSerialOf(strings.ToLower(state.Zone)),
 		Refresh: 7200,
 		Retry:   1800,
 		Expire:  86400,
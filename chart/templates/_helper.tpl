@@ -112,14 +112,14 @@ false
 {{/*
   k8s-gateway.gatewayAPIs:
   Returns "true" if any one of the Gateway API resources
-  (HTTPRoute, TLSRoute, GRPCRoute) is in .Values.watchedResources,
+  (Gateway, HTTPRoute, TLSRoute, GRPCRoute, TCPRoute, UDPRoute) is in .Values.watchedResources,
   or if watchedResources is not set; returns "false" otherwise.
 */}}
 {{- define "k8s-gateway.gatewayAPI" -}}
   {{- if .Values.watchedResources -}}
     {{- $found := false -}}
     {{- range .Values.watchedResources -}}
-      {{- if or (eq . "HTTPRoute") (eq . "TLSRoute") (eq . "GRPCRoute") -}}
+      {{- if has . (list "Gateway" "HTTPRoute" "TLSRoute" "GRPCRoute" "TCPRoute" "UDPRoute") -}}
         {{- $found = true -}}
       {{- end -}}
     {{- end -}}
@@ -210,3 +210,325 @@ false
 false
   {{- end -}}
 {{- end }}
+
+{{/*
+  k8s-gateway.istio:
+  Returns "true" if "VirtualService" or "IstioGateway" is in .Values.watchedResources,
+  otherwise returns "false".
+*/}}
+{{- define "k8s-gateway.istio" -}}
+  {{- if .Values.watchedResources -}}
+    {{- $found := false -}}
+    {{- range .Values.watchedResources -}}
+      {{- if has . (list "VirtualService" "IstioGateway") -}}
+        {{- $found = true -}}
+      {{- end -}}
+    {{- end -}}
+    {{- if $found -}}
+true
+    {{- else -}}
+false
+    {{- end -}}
+  {{- else -}}
+false
+  {{- end -}}
+{{- end }}
+
+{{/*
+  k8s-gateway.openshift:
+  Returns "true" if "Route" is in .Values.watchedResources,
+  otherwise returns "false".
+*/}}
+{{- define "k8s-gateway.openshift" -}}
+  {{- if .Values.watchedResources -}}
+    {{- $found := false -}}
+    {{- range .Values.watchedResources -}}
+      {{- if eq . "Route" -}}
+        {{- $found = true -}}
+      {{- end -}}
+    {{- end -}}
+    {{- if $found -}}
+true
+    {{- else -}}
+false
+    {{- end -}}
+  {{- else -}}
+false
+  {{- end -}}
+{{- end }}
+
+{{/*
+  k8s-gateway.traefik:
+  Returns "true" if "IngressRoute" or "IngressRouteTCP" is in .Values.watchedResources,
+  otherwise returns "false".
+*/}}
+{{- define "k8s-gateway.traefik" -}}
+  {{- if .Values.watchedResources -}}
+    {{- $found := false -}}
+    {{- range .Values.watchedResources -}}
+      {{- if has . (list "IngressRoute" "IngressRouteTCP") -}}
+        {{- $found = true -}}
+      {{- end -}}
+    {{- end -}}
+    {{- if $found -}}
+true
+    {{- else -}}
+false
+    {{- end -}}
+  {{- else -}}
+false
+  {{- end -}}
+{{- end }}
+
+{{/*
+  k8s-gateway.contour:
+  Returns "true" if "HTTPProxy" is in .Values.watchedResources,
+  otherwise returns "false".
+*/}}
+{{- define "k8s-gateway.contour" -}}
+  {{- if .Values.watchedResources -}}
+    {{- $found := false -}}
+    {{- range .Values.watchedResources -}}
+      {{- if eq . "HTTPProxy" -}}
+        {{- $found = true -}}
+      {{- end -}}
+    {{- end -}}
+    {{- if $found -}}
+true
+    {{- else -}}
+false
+    {{- end -}}
+  {{- else -}}
+false
+  {{- end -}}
+{{- end }}
+
+{{/*
+  k8s-gateway.serviceImport:
+  Returns "true" if "ServiceImport" is in .Values.watchedResources,
+  otherwise returns "false".
+*/}}
+{{- define "k8s-gateway.serviceImport" -}}
+  {{- if .Values.watchedResources -}}
+    {{- $found := false -}}
+    {{- range .Values.watchedResources -}}
+      {{- if eq . "ServiceImport" -}}
+        {{- $found = true -}}
+      {{- end -}}
+    {{- end -}}
+    {{- if $found -}}
+true
+    {{- else -}}
+false
+    {{- end -}}
+  {{- else -}}
+false
+  {{- end -}}
+{{- end }}
+
+{{/*
+  k8s-gateway.pod:
+  Returns "true" if "Pod" is in .Values.watchedResources,
+  otherwise returns "false".
+*/}}
+{{- define "k8s-gateway.pod" -}}
+  {{- if .Values.watchedResources -}}
+    {{- $found := false -}}
+    {{- range .Values.watchedResources -}}
+      {{- if eq . "Pod" -}}
+        {{- $found = true -}}
+      {{- end -}}
+    {{- end -}}
+    {{- if $found -}}
+true
+    {{- else -}}
+false
+    {{- end -}}
+  {{- else -}}
+false
+  {{- end -}}
+{{- end }}
+
+{{/*
+  k8s-gateway.namespacedRules:
+  Rules for the namespaced resources, granted cluster-wide by the ClusterRole or
+  per watched namespace by a Role when filters.namespaces is set. Namespaces are
+  cluster-scoped, so they are only granted along with the ClusterRole.
+*/}}
+{{- define "k8s-gateway.namespacedRules" }}
+  {{- if eq (include "k8s-gateway.service" .) "true" }}
+- apiGroups:
+  - ""
+  resources:
+  - services
+  {{- if ne (include "k8s-gateway.namespaceScoped" .) "true" }}
+  - namespaces
+  {{- end }}
+  verbs:
+  - list
+  - watch
+- apiGroups:
+  - discovery.k8s.io
+  resources:
+  - endpointslices
+  verbs:
+  - list
+  - watch
+  {{- end }}
+  {{- if eq (include "k8s-gateway.ingress" .) "true" }}
+- apiGroups:
+  - extensions
+  - networking.k8s.io
+  resources:
+  - ingresses
+  verbs:
+  - list
+  - watch
+  {{- end }}
+  {{- if eq (include "k8s-gateway.gatewayAPI" .) "true" }}
+- apiGroups:
+  - gateway.networking.k8s.io
+  resources:
+  - "*"
+  verbs:
+  - "watch"
+  - "list"
+  {{- end }}
+  {{- if eq (include "k8s-gateway.istio" .) "true" }}
+- apiGroups:
+  - networking.istio.io
+  resources:
+  - gateways
+  - virtualservices
+  verbs:
+  - watch
+  - list
+- apiGroups:
+  - ""
+  resources:
+  - services
+  verbs:
+  - list
+  - watch
+  {{- end }}
+  {{- if eq (include "k8s-gateway.openshift" .) "true" }}
+- apiGroups:
+  - route.openshift.io
+  resources:
+  - routes
+  verbs:
+  - watch
+  - list
+- apiGroups:
+  - ""
+  resources:
+  - services
+  verbs:
+  - list
+  - watch
+  {{- end }}
+  {{- if eq (include "k8s-gateway.traefik" .) "true" }}
+- apiGroups:
+  - traefik.io
+  resources:
+  - ingressroutes
+  - ingressroutetcps
+  verbs:
+  - watch
+  - list
+- apiGroups:
+  - ""
+  resources:
+  - services
+  verbs:
+  - list
+  - watch
+  {{- end }}
+  {{- if eq (include "k8s-gateway.contour" .) "true" }}
+- apiGroups:
+  - projectcontour.io
+  resources:
+  - httpproxies
+  verbs:
+  - watch
+  - list
+  {{- end }}
+  {{- if eq (include "k8s-gateway.serviceImport" .) "true" }}
+- apiGroups:
+  - multicluster.x-k8s.io
+  resources:
+  - serviceimports
+  verbs:
+  - watch
+  - list
+- apiGroups:
+  - discovery.k8s.io
+  resources:
+  - endpointslices
+  verbs:
+  - list
+  - watch
+  {{- end }}
+  {{- if eq (include "k8s-gateway.pod" .) "true" }}
+- apiGroups:
+  - ""
+  resources:
+  - pods
+  - services
+  verbs:
+  - list
+  - watch
+  {{- end }}
+  {{- if eq (include "k8s-gateway.dnsEndpoint" .) "true" }}
+- apiGroups:
+  - externaldns.k8s.io
+  resources:
+  - dnsendpoints
+  verbs:
+  - get
+  - watch
+  - list
+- apiGroups:
+  - externaldns.k8s.io
+  resources:
+    - dnsendpoints/status
+  verbs:
+    - "*"
+  {{- end }}
+{{- end }}
+
+{{/*
+  k8s-gateway.namespaceScoped:
+  Returns "true" if only the namespaces in filters.namespaces are watched, so that
+  the namespaced resources can be granted by a Role in each of them. Otherwise
+  returns "false".
+*/}}
+{{- define "k8s-gateway.namespaceScoped" -}}
+  {{- if and .Values.filters.namespaces (not .Values.filters.namespaceLabelSelector) -}}
+true
+  {{- else -}}
+false
+  {{- end -}}
+{{- end }}
+
+{{/*
+  k8s-gateway.serviceNamespaces:
+  Returns the namespaces, as a JSON list, of the Services exposing Traefik, the
+  OpenShift routers and the Istio gateways, which are watched in addition to
+  filters.namespaces.
+*/}}
+{{- define "k8s-gateway.serviceNamespaces" -}}
+  {{- $namespaces := list -}}
+  {{- if eq (include "k8s-gateway.traefik" .) "true" -}}
+    {{- range .Values.filters.traefikServices -}}
+      {{- $namespaces = append $namespaces (first (splitList "/" .)) -}}
+    {{- end -}}
+  {{- end -}}
+  {{- if eq (include "k8s-gateway.openshift" .) "true" -}}
+    {{- $namespaces = append $namespaces "openshift-ingress" -}}
+  {{- end -}}
+  {{- if eq (include "k8s-gateway.istio" .) "true" -}}
+    {{- $namespaces = append $namespaces "istio-system" -}}
+  {{- end -}}
+  {{- uniq $namespaces | toJson -}}
+{{- end }}
@@ -0,0 +1,199 @@
+package gateway
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	httpProxyHostnameIndex = "httpProxyHostname"
+	// contourIngressClassAnnotationKey is the ingress class annotation of HTTPProxies
+	// predating spec.ingressClassName
+	contourIngressClassAnnotationKey = "projectcontour.io/ingress.class"
+	legacyIngressClassAnnotationKey  = "kubernetes.io/ingress.class"
+)
+
+var contourCRDClient rest.Interface
+
+var contourGroupVersion = schema.GroupVersion{Group: "projectcontour.io", Version: "v1"}
+
+// httpProxy holds the fields of a projectcontour.io HTTPProxy needed to publish it,
+// so that no Contour dependency is pulled in.
+type httpProxy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              httpProxySpec   `json:"spec"`
+	Status            httpProxyStatus `json:"status,omitempty"`
+}
+
+type httpProxySpec struct {
+	// VirtualHost is only set on root proxies, included proxies inherit it
+	VirtualHost      *httpProxyVirtualHost `json:"virtualhost,omitempty"`
+	IngressClassName string                `json:"ingressClassName,omitempty"`
+}
+
+type httpProxyVirtualHost struct {
+	Fqdn string `json:"fqdn"`
+}
+
+type httpProxyStatus struct {
+	LoadBalancer core.LoadBalancerStatus `json:"loadBalancer,omitempty"`
+}
+
+type httpProxyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []httpProxy `json:"items"`
+}
+
+func (in *httpProxy) DeepCopyObject() runtime.Object {
+	out := &httpProxy{TypeMeta: in.TypeMeta, Spec: in.Spec}
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if in.Spec.VirtualHost != nil {
+		virtualHost := *in.Spec.VirtualHost
+		out.Spec.VirtualHost = &virtualHost
+	}
+	in.Status.LoadBalancer.DeepCopyInto(&out.Status.LoadBalancer)
+	return out
+}
+
+func (in *httpProxyList) DeepCopyObject() runtime.Object {
+	out := &httpProxyList{TypeMeta: in.TypeMeta}
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	for i := range in.Items {
+		out.Items = append(out.Items, *in.Items[i].DeepCopyObject().(*httpProxy))
+	}
+	return out
+}
+
+func addContourTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypeWithName(contourGroupVersion.WithKind("HTTPProxy"), &httpProxy{})
+	scheme.AddKnownTypeWithName(contourGroupVersion.WithKind("HTTPProxyList"), &httpProxyList{})
+	metav1.AddToGroupVersion(scheme, contourGroupVersion)
+	return nil
+}
+
+func newContourRESTClient(config *rest.Config) (rest.Interface, error) {
+	return newCRDRESTClient(config, contourGroupVersion, addContourTypes)
+}
+
+func initializeHTTPProxyController(ctx context.Context, ctrl *KubeController, gw *Gateway) {
+	if !slices.Contains(dereferenceStrings(gw.ConfiguredResources), "HTTPProxy") {
+		return
+	}
+	resource := gw.lookupResource("HTTPProxy")
+	if resource == nil || contourCRDClient == nil {
+		return
+	}
+	if !crdServesVersion(apiextensionsClient, "httpproxies.projectcontour.io", contourGroupVersion.Version) {
+		return
+	}
+
+	httpProxyControllers := ctrl.forEachNamespace(func(ns string) cache.SharedIndexInformer {
+		return cache.NewSharedIndexInformer(
+			&cache.ListWatch{
+				ListFunc:  httpProxyLister(ctx, ns),
+				WatchFunc: httpProxyWatcher(ctx, ns),
+			},
+			&httpProxy{},
+			defaultResyncPeriod,
+			cache.Indexers{httpProxyHostnameIndex: httpProxyHostnameIndexFunc},
+		)
+	})
+	resource.lookup = lookupHTTPProxyIndex(httpProxyControllers, gw.resourceFilters.ingressClasses)
+	resource.keys = listIndexKeys(httpProxyHostnameIndex, httpProxyControllers...)
+	ctrl.addController("HTTPProxy", httpProxyControllers...)
+	log.Infof("HTTPProxy controller initialized")
+}
+
+func httpProxyWatcher(ctx context.Context, ns string) func(metav1.ListOptions) (watch.Interface, error) {
+	return func(opts metav1.ListOptions) (watch.Interface, error) {
+		opts.Watch = true
+		return contourCRDClient.Get().
+			Resource("httpproxies").
+			Namespace(ns).
+			VersionedParams(&opts, metav1.ParameterCodec).
+			Watch(ctx)
+	}
+}
+
+func httpProxyLister(ctx context.Context, ns string) func(metav1.ListOptions) (runtime.Object, error) {
+	return func(opts metav1.ListOptions) (runtime.Object, error) {
+		return contourCRDClient.Get().
+			Resource("httpproxies").
+			Namespace(ns).
+			VersionedParams(&opts, metav1.ParameterCodec).
+			Do(ctx).
+			Get()
+	}
+}
+
+func httpProxyHostnameIndexFunc(obj interface{}) ([]string, error) {
+	proxy, ok := obj.(*httpProxy)
+	if !ok {
+		return []string{}, nil
+	}
+
+	if checkIgnoreLabel(proxy.Labels) {
+		log.Debugf("Ignoring httpProxy %s due to %s label", proxy.Name, ignoreLabelKey)
+		return []string{}, nil
+	}
+
+	// only root proxies own a hostname
+	if proxy.Spec.VirtualHost == nil || proxy.Spec.VirtualHost.Fqdn == "" {
+		return []string{}, nil
+	}
+
+	hostname := strings.ToLower(proxy.Spec.VirtualHost.Fqdn)
+	log.Debugf("Adding index %s for httpProxy %s", hostname, proxy.Name)
+	return []string{hostname}, nil
+}
+
+// httpProxyIngressClass returns the ingress class of an HTTPProxy, set either in
+// its spec or by one of the ingress class annotations
+func httpProxyIngressClass(proxy *httpProxy) string {
+	if proxy.Spec.IngressClassName != "" {
+		return proxy.Spec.IngressClassName
+	}
+	for _, key := range []string{contourIngressClassAnnotationKey, legacyIngressClassAnnotationKey} {
+		if class, ok := proxy.Annotations[key]; ok {
+			return class
+		}
+	}
+	return ""
+}
+
+func lookupHTTPProxyIndex(ctrl informers, ingclasses []string) func([]string) lookupResult {
+	return func(indexKeys []string) (result lookupResult) {
+		var objs []interface{}
+		for _, key := range indexKeys {
+			objs = append(objs, ctrl.byIndex(httpProxyHostnameIndex, strings.ToLower(key))...)
+		}
+		log.Debugf("Found %d matching HTTPProxy objects", len(objs))
+		for _, obj := range objs {
+			proxy, _ := obj.(*httpProxy)
+
+			if class := httpProxyIngressClass(proxy); len(ingclasses) > 0 && !slices.Contains(ingclasses, class) {
+				log.Debugf("Skipping httpProxy of '%s' ingressClass", class)
+				continue
+			}
+
+			addrs, hostnames := fetchServiceLoadBalancerIPs(proxy.Status.LoadBalancer.Ingress)
+			result.lowerTTL(annotationTTL(proxy.ObjectMeta))
+			result.observe(proxy)
+			result.addrs = append(result.addrs, addrs...)
+			result.hostnames = append(result.hostnames, hostnames...)
+		}
+
+		return
+	}
+}
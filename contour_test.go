@@ -0,0 +1,85 @@
+package gateway
+
+import (
+	"slices"
+	"testing"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestLookupHTTPProxy(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		httpProxyHostnameIndex: httpProxyHostnameIndexFunc,
+	})
+	loadBalancer := func(ip string) httpProxyStatus {
+		return httpProxyStatus{LoadBalancer: core.LoadBalancerStatus{Ingress: []core.LoadBalancerIngress{{IP: ip}}}}
+	}
+
+	for _, proxy := range []*httpProxy{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "root", Namespace: "team-a"},
+			Spec:       httpProxySpec{VirtualHost: &httpProxyVirtualHost{Fqdn: "App.example.com"}, IngressClassName: "contour"},
+			Status:     loadBalancer("192.0.2.50"),
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "annotated",
+				Namespace:   "team-a",
+				Annotations: map[string]string{contourIngressClassAnnotationKey: "contour"},
+			},
+			Spec:   httpProxySpec{VirtualHost: &httpProxyVirtualHost{Fqdn: "annotated.example.com"}},
+			Status: loadBalancer("192.0.2.51"),
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "internal", Namespace: "team-a"},
+			Spec:       httpProxySpec{VirtualHost: &httpProxyVirtualHost{Fqdn: "internal.example.com"}, IngressClassName: "contour-internal"},
+			Status:     loadBalancer("192.0.2.52"),
+		},
+		{
+			// included proxies have no virtual host, even if they report the load balancer
+			ObjectMeta: metav1.ObjectMeta{Name: "included", Namespace: "team-a"},
+			Status:     loadBalancer("192.0.2.53"),
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "ignored", Namespace: "team-a", Labels: map[string]string{ignoreLabelKey: "true"}},
+			Spec:       httpProxySpec{VirtualHost: &httpProxyVirtualHost{Fqdn: "ignored.example.com"}, IngressClassName: "contour"},
+			Status:     loadBalancer("192.0.2.54"),
+		},
+	} {
+		if err := indexer.Add(proxy); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys := indexer.ListIndexFuncValues(httpProxyHostnameIndex)
+	slices.Sort(keys)
+	if expected := []string{"annotated.example.com", "app.example.com", "internal.example.com"}; !slices.Equal(keys, expected) {
+		t.Errorf("Expected index keys %v, got %v", expected, keys)
+	}
+
+	ctrl := informers{&fakeSharedIndexInformer{indexer: indexer}}
+	tests := []struct {
+		ingclasses []string
+		indexKeys  []string
+		expected   []string
+	}{
+		{nil, []string{"app.example.com"}, []string{"192.0.2.50"}},
+		{nil, []string{"internal.example.com"}, []string{"192.0.2.52"}},
+		{[]string{"contour"}, []string{"app.example.com"}, []string{"192.0.2.50"}},
+		{[]string{"contour"}, []string{"annotated.example.com"}, []string{"192.0.2.51"}},
+		{[]string{"contour"}, []string{"internal.example.com"}, nil},
+		{nil, []string{"ignored.example.com"}, nil},
+	}
+
+	for i, tc := range tests {
+		var got []string
+		for _, addr := range lookupHTTPProxyIndex(ctrl, tc.ingclasses)(tc.indexKeys).addrs {
+			got = append(got, addr.String())
+		}
+		if !slices.Equal(got, tc.expected) {
+			t.Errorf("Test %d: expected addresses %v, got %v", i, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package gateway
+
+import (
+	"net/netip"
+	"strings"
+
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/request"
+	"github.com/miekg/dns"
+)
+
+// delegation returns the NS records of the zone cut name is at or below, nil if
+// name isn't delegated. Sub-delegations are published as NS records of DNSEndpoints.
+func (gw *Gateway) delegation(name, zone string) []dns.RR {
+	resource := gw.lookupResource("DNSEndpoint")
+	if resource == nil {
+		return nil
+	}
+
+	base, _ := dnsutil.TrimZone(name, zone)
+	if base == "" {
+		return nil
+	}
+	labels := dns.SplitDomainName(base)
+
+	// The cut closest to the zone apex is authoritative for everything below it
+	for i := len(labels) - 1; i >= 0; i-- {
+		cut := strings.Join(labels[i:], ".") + "." + zone
+		result := resource.lookup(gw.getQueryIndexKeys(cut, zone))
+		if ns := result.recordsOfType(dns.TypeNS); len(ns) > 0 {
+			return gw.records(cut, gw.recordTTL(result), ns)
+		}
+	}
+	return nil
+}
+
+// serveReferral answers queries at or below a zone cut with a referral to the
+// delegated nameservers. Addresses of nameservers inside the delegated zone are
+// added as glue.
+func (gw *Gateway) serveReferral(state request.Request, ns []dns.RR) (int, error) {
+	m := new(dns.Msg)
+	m.SetReply(state.Req)
+	m.Ns = ns
+
+	cut := ns[0].Header().Name
+	for _, rr := range ns {
+		target := rr.(*dns.NS).Ns
+		if !dns.IsSubDomain(cut, target) {
+			continue
+		}
+		result := gw.getMatchingAddresses(gw.getQueryIndexKeySets(target, state.Zone))
+		var ipv4Addrs, ipv6Addrs []netip.Addr
+		for _, addr := range result.addrs {
+			if addr.Is4() {
+				ipv4Addrs = append(ipv4Addrs, addr)
+			}
+			if addr.Is6() {
+				ipv6Addrs = append(ipv6Addrs, addr)
+			}
+		}
+		ttl := gw.recordTTL(result)
+		m.Extra = append(m.Extra, gw.A(target, ttl, ipv4Addrs)...)
+		m.Extra = append(m.Extra, gw.AAAA(target, ttl, ipv6Addrs)...)
+	}
+
+	if err := state.W.WriteMsg(m); err != nil {
+		log.Errorf("failed to send a response: %s", err)
+	}
+	return dns.RcodeSuccess, nil
+}
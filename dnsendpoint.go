@@ -2,14 +2,16 @@ package gateway
 
 import (
 	"context"
+	"errors"
+	"math"
 	"net/netip"
 	"slices"
 	"strings"
 
+	"github.com/miekg/dns"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
@@ -23,18 +25,7 @@ const (
 var externaldnsCRDClient rest.Interface
 
 func newExternalDNSRESTClient(config *rest.Config) (rest.Interface, error) {
-	scheme := runtime.NewScheme()
-	if err := externaldnsv1.AddToScheme(scheme); err != nil {
-		return nil, err
-	}
-	gv := schema.GroupVersion{Group: "externaldns.k8s.io", Version: "v1alpha1"}
-	cfgCopy := *config
-	cfgCopy.GroupVersion = &gv
-	cfgCopy.APIPath = "/apis"
-	cfgCopy.NegotiatedSerializer = serializer.WithoutConversionCodecFactory{
-		CodecFactory: serializer.NewCodecFactory(scheme),
-	}
-	return rest.RESTClientFor(&cfgCopy)
+	return newCRDRESTClient(config, schema.GroupVersion{Group: "externaldns.k8s.io", Version: "v1alpha1"}, externaldnsv1.AddToScheme)
 }
 
 func initializeDNSEndpointController(ctx context.Context, ctrl *KubeController, gw *Gateway) {
@@ -49,17 +40,20 @@ func initializeDNSEndpointController(ctx context.Context, ctrl *KubeController,
 		return
 	}
 
-	dnsEndpointController := cache.NewSharedIndexInformer(
-		&cache.ListWatch{
-			WatchFunc: dnsEndpointWatcher(ctx, ""),
-			ListFunc:  dnsEndpointLister(ctx, ""),
-		},
-		&externaldnsv1.DNSEndpoint{},
-		defaultResyncPeriod,
-		cache.Indexers{externalDNSHostnameIndex: dnsEndpointTargetIndexFunc},
-	)
-	resource.lookup = lookupDNSEndpoint(dnsEndpointController)
-	ctrl.controllers = append(ctrl.controllers, dnsEndpointController)
+	dnsEndpointControllers := ctrl.forEachNamespace(func(ns string) cache.SharedIndexInformer {
+		return cache.NewSharedIndexInformer(
+			&cache.ListWatch{
+				WatchFunc: dnsEndpointWatcher(ctx, ns),
+				ListFunc:  dnsEndpointLister(ctx, ns),
+			},
+			&externaldnsv1.DNSEndpoint{},
+			defaultResyncPeriod,
+			cache.Indexers{externalDNSHostnameIndex: dnsEndpointTargetIndexFunc},
+		)
+	})
+	resource.lookup = lookupDNSEndpoint(dnsEndpointControllers)
+	resource.keys = listIndexKeys(externalDNSHostnameIndex, dnsEndpointControllers...)
+	ctrl.addController("DNSEndpoint", dnsEndpointControllers...)
 	log.Infof("DNSEndpoint controller initialized")
 }
 
@@ -104,32 +98,78 @@ func dnsEndpointTargetIndexFunc(obj interface{}) ([]string, error) {
 	return hostnames, nil
 }
 
-func lookupDNSEndpoint(ctrl cache.SharedIndexInformer) func([]string) (results []netip.Addr, raws []string) {
-	return func(indexKeys []string) (result []netip.Addr, raw []string) {
+func lookupDNSEndpoint(ctrl informers) func([]string) lookupResult {
+	return func(indexKeys []string) (result lookupResult) {
 		var objs []interface{}
 		for _, key := range indexKeys {
-			obj, _ := ctrl.GetIndexer().ByIndex(externalDNSHostnameIndex, strings.ToLower(key))
-			objs = append(objs, obj...)
+			objs = append(objs, ctrl.byIndex(externalDNSHostnameIndex, strings.ToLower(key))...)
 		}
 		log.Debugf("Found %d matching DNSEndpoint objects", len(objs))
 		for _, obj := range objs {
 			dnsEndpoint, _ := obj.(*externaldnsv1.DNSEndpoint)
 
 			for _, endpoint := range dnsEndpoint.Spec.Endpoints {
+				// a DNSEndpoint may hold the endpoints of other names as well
+				if !slices.ContainsFunc(indexKeys, func(key string) bool { return strings.EqualFold(key, endpoint.DNSName) }) {
+					continue
+				}
+				result.observe(dnsEndpoint)
+				if endpoint.RecordTTL.IsConfigured() && endpoint.RecordTTL <= math.MaxUint32 {
+					result.lowerTTL(uint32(endpoint.RecordTTL))
+				}
 				for _, target := range endpoint.Targets {
-					if endpoint.RecordType == "A" || endpoint.RecordType == "AAAA" {
+					switch endpoint.RecordType {
+					case "A", "AAAA":
 						addr, err := netip.ParseAddr(target)
 						if err != nil {
 							continue
 						}
-						result = append(result, addr)
-					}
-					if endpoint.RecordType == "TXT" {
-						raw = append(raw, target)
+						result.addrs = append(result.addrs, addr)
+					case "TXT":
+						result.raws = append(result.raws, target)
+					case "CNAME":
+						result.hostnames = append(result.hostnames, strings.TrimSuffix(target, "."))
+						result.alias = true
+					default:
+						rr, err := parseEndpointRecord(endpoint.RecordType, target)
+						if err != nil {
+							log.Debugf("Ignoring %s record %q of DNSEndpoint %s/%s: %s", endpoint.RecordType, target, dnsEndpoint.Namespace, dnsEndpoint.Name, err)
+							continue
+						}
+						result.records = append(result.records, rr)
 					}
 				}
 			}
 		}
-		return result, raw
+		return result
+	}
+}
+
+// dnsEndpointRecordTypes are the record types besides A, AAAA, TXT and CNAME that
+// DNSEndpoints can publish. Their targets hold the record data in zone file format.
+var dnsEndpointRecordTypes = map[string]uint16{
+	"MX":    dns.TypeMX,
+	"SRV":   dns.TypeSRV,
+	"NS":    dns.TypeNS,
+	"CAA":   dns.TypeCAA,
+	"PTR":   dns.TypePTR,
+	"NAPTR": dns.TypeNAPTR,
+}
+
+// parseEndpointRecord parses the target of an endpoint into a record without owner
+// name. Names in the record data are taken to be fully qualified, e.g.
+// "10 mail.example.com" for an MX record.
+func parseEndpointRecord(recordType, target string) (dns.RR, error) {
+	rrtype, ok := dnsEndpointRecordTypes[recordType]
+	if !ok {
+		return nil, errors.New("unsupported record type")
+	}
+	rr, err := dns.NewRR(". IN " + recordType + " " + target)
+	if err != nil {
+		return nil, err
+	}
+	if rr == nil || rr.Header().Rrtype != rrtype {
+		return nil, errors.New("no record data")
 	}
+	return rr, nil
 }
@@ -9,6 +9,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -201,14 +204,37 @@ func TestLookupDNSEndpoint(t *testing.T) {
 		t.Fatalf("failed to add DNSEndpoint to indexer: %v", err)
 	}
 
-	lookup := lookupDNSEndpoint(&fakeSharedIndexInformer{indexer: fakeIndexer})
-	result, raw := lookup([]string{"svc.example.com"})
+	lookup := lookupDNSEndpoint(informers{&fakeSharedIndexInformer{indexer: fakeIndexer}})
+	result := lookup([]string{"svc.example.com"})
 
-	if len(result) != 2 {
-		t.Errorf("expected 2 IP results (1 A + 1 AAAA), got %d: %v", len(result), result)
+	if len(result.addrs) != 2 {
+		t.Errorf("expected 2 IP results (1 A + 1 AAAA), got %d: %v", len(result.addrs), result.addrs)
 	}
-	if len(raw) != 1 || raw[0] != "heritage=external-dns" {
-		t.Errorf("expected 1 TXT result %q, got %v", "heritage=external-dns", raw)
+	if len(result.raws) != 1 || result.raws[0] != "heritage=external-dns" {
+		t.Errorf("expected 1 TXT result %q, got %v", "heritage=external-dns", result.raws)
+	}
+}
+
+func TestLookupDNSEndpoint_RecordTTL(t *testing.T) {
+	fakeIndexer := newDNSEndpointIndexer()
+	ep := &externaldnsv1.DNSEndpoint{
+		ObjectMeta: metav1.ObjectMeta{Name: "ep1", Namespace: "ns1"},
+		Spec: externaldnsv1.DNSEndpointSpec{
+			Endpoints: []*endpoint.Endpoint{
+				{DNSName: "svc.example.com", RecordType: "A", Targets: []string{"192.0.2.1"}, RecordTTL: 120},
+				{DNSName: "svc.example.com", RecordType: "AAAA", Targets: []string{"2001:db8::1"}, RecordTTL: 30},
+				// not configured
+				{DNSName: "svc.example.com", RecordType: "TXT", Targets: []string{"heritage=external-dns"}},
+			},
+		},
+	}
+	if err := fakeIndexer.Add(ep); err != nil {
+		t.Fatalf("failed to add DNSEndpoint to indexer: %v", err)
+	}
+
+	lookup := lookupDNSEndpoint(informers{&fakeSharedIndexInformer{indexer: fakeIndexer}})
+	if result := lookup([]string{"svc.example.com"}); result.ttl != 30 {
+		t.Errorf("expected the lowest record TTL 30, got %d", result.ttl)
 	}
 }
 
@@ -226,27 +252,27 @@ func TestLookupDNSEndpoint_InvalidIP(t *testing.T) {
 		t.Fatalf("failed to add DNSEndpoint to indexer: %v", err)
 	}
 
-	lookup := lookupDNSEndpoint(&fakeSharedIndexInformer{indexer: fakeIndexer})
-	result, _ := lookup([]string{"bad.example.com"})
+	lookup := lookupDNSEndpoint(informers{&fakeSharedIndexInformer{indexer: fakeIndexer}})
+	result := lookup([]string{"bad.example.com"})
 
-	if len(result) != 1 {
-		t.Errorf("expected 1 valid IP (invalid one skipped), got %d: %v", len(result), result)
+	if len(result.addrs) != 1 {
+		t.Errorf("expected 1 valid IP (invalid one skipped), got %d: %v", len(result.addrs), result.addrs)
 	}
-	if result[0].String() != "192.0.2.5" {
-		t.Errorf("expected 192.0.2.5, got %s", result[0])
+	if result.addrs[0].String() != "192.0.2.5" {
+		t.Errorf("expected 192.0.2.5, got %s", result.addrs[0])
 	}
 }
 
 func TestLookupDNSEndpoint_NoMatch(t *testing.T) {
 	fakeIndexer := newDNSEndpointIndexer()
-	lookup := lookupDNSEndpoint(&fakeSharedIndexInformer{indexer: fakeIndexer})
-	result, raw := lookup([]string{"unknown.example.com"})
+	lookup := lookupDNSEndpoint(informers{&fakeSharedIndexInformer{indexer: fakeIndexer}})
+	result := lookup([]string{"unknown.example.com"})
 
-	if len(result) != 0 {
-		t.Errorf("expected no IP results, got: %v", result)
+	if len(result.addrs) != 0 {
+		t.Errorf("expected no IP results, got: %v", result.addrs)
 	}
-	if len(raw) != 0 {
-		t.Errorf("expected no raw results, got: %v", raw)
+	if len(result.raws) != 0 {
+		t.Errorf("expected no raw results, got: %v", result.raws)
 	}
 }
 
@@ -274,3 +300,148 @@ func TestDNSEndpointLister(t *testing.T) {
 		t.Errorf("expected item name %q, got %q", "ep1", list.Items[0].Name)
 	}
 }
+
+var testsDNSEndpointRecords = []test.Case{
+	// MX record | Test 0
+	{
+		Qname: "mail.example.com.", Qtype: dns.TypeMX, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.MX("mail.example.com.  60  IN  MX  10 mx1.example.com."),
+			test.MX("mail.example.com.  60  IN  MX  20 mx2.example.com."),
+		},
+	},
+	// Name holding other records only | Test 1
+	{
+		Qname: "mail.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Ns: []dns.RR{
+			test.SOA("example.com.  60  IN  SOA dns1.kube-system.example.com. hostmaster.example.com. 1499347823 7200 1800 86400 5"),
+		},
+	},
+	// Name that doesn't exist | Test 2
+	{
+		Qname: "nomail.example.com.", Qtype: dns.TypeMX, Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{
+			test.SOA("example.com.  60  IN  SOA dns1.kube-system.example.com. hostmaster.example.com. 1499347823 7200 1800 86400 5"),
+		},
+	},
+	// SRV record | Test 3
+	{
+		Qname: "_sip._tcp.example.com.", Qtype: dns.TypeSRV, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.SRV("_sip._tcp.example.com.  60  IN  SRV  0 5 5060 sip.example.com."),
+		},
+	},
+	// CAA record with its own TTL | Test 4
+	{
+		Qname: "example.com.", Qtype: dns.TypeCAA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			&dns.CAA{Hdr: dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeCAA, Class: dns.ClassINET, Ttl: 3600}, Flag: 0, Tag: "issue", Value: "letsencrypt.org"},
+		},
+	},
+	// CNAME answers every query type | Test 5
+	{
+		Qname: "alias.example.com.", Qtype: dns.TypeMX, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.CNAME("alias.example.com.  60  IN  CNAME  mail.example.com."),
+		},
+	},
+	// CNAME to an in-zone name is chased | Test 6
+	{
+		Qname: "www.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.A("web.example.com.  60  IN  A  192.0.2.10"),
+			test.CNAME("www.example.com.  60  IN  CNAME  web.example.com."),
+		},
+	},
+	// Referral at the zone cut, with glue | Test 7
+	{
+		Qname: "sub.example.com.", Qtype: dns.TypeNS, Rcode: dns.RcodeSuccess,
+		Ns: []dns.RR{
+			test.NS("sub.example.com.  60  IN  NS  ns.other.org."),
+			test.NS("sub.example.com.  60  IN  NS  ns1.sub.example.com."),
+		},
+		Extra: []dns.RR{
+			test.A("ns1.sub.example.com.  60  IN  A  192.0.2.53"),
+		},
+	},
+	// Referral below the zone cut | Test 8
+	{
+		Qname: "host.sub.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Ns: []dns.RR{
+			test.NS("sub.example.com.  60  IN  NS  ns.other.org."),
+			test.NS("sub.example.com.  60  IN  NS  ns1.sub.example.com."),
+		},
+		Extra: []dns.RR{
+			test.A("ns1.sub.example.com.  60  IN  A  192.0.2.53"),
+		},
+	},
+	// DS of the zone cut is answered by the parent | Test 9
+	{
+		Qname: "sub.example.com.", Qtype: dns.TypeDS, Rcode: dns.RcodeSuccess,
+		Ns: []dns.RR{
+			test.SOA("example.com.  60  IN  SOA dns1.kube-system.example.com. hostmaster.example.com. 1499347823 7200 1800 86400 5"),
+		},
+	},
+	// PTR record in a reverse zone | Test 10
+	{
+		Qname: "10.2.0.192.in-addr.arpa.", Qtype: dns.TypePTR, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.PTR("10.2.0.192.in-addr.arpa.  60  IN  PTR  web.example.com."),
+		},
+	},
+}
+
+func TestPluginDNSEndpointRecords(t *testing.T) {
+	fakeIndexer := newDNSEndpointIndexer()
+	ep := &externaldnsv1.DNSEndpoint{
+		ObjectMeta: metav1.ObjectMeta{Name: "ep1", Namespace: "ns1"},
+		Spec: externaldnsv1.DNSEndpointSpec{
+			Endpoints: []*endpoint.Endpoint{
+				{DNSName: "mail.example.com", RecordType: "MX", Targets: []string{"10 mx1.example.com", "20 mx2.example.com."}},
+				{DNSName: "_sip._tcp.example.com", RecordType: "SRV", Targets: []string{"0 5 5060 sip.example.com"}},
+				{DNSName: "example.com", RecordType: "CAA", Targets: []string{`0 issue "letsencrypt.org"`}, RecordTTL: 3600},
+				{DNSName: "alias.example.com", RecordType: "CNAME", Targets: []string{"mail.example.com"}},
+				{DNSName: "www.example.com", RecordType: "CNAME", Targets: []string{"web.example.com"}},
+				{DNSName: "web.example.com", RecordType: "A", Targets: []string{"192.0.2.10"}},
+				{DNSName: "sub.example.com", RecordType: "NS", Targets: []string{"ns1.sub.example.com", "ns.other.org"}},
+				{DNSName: "ns1.sub.example.com", RecordType: "A", Targets: []string{"192.0.2.53"}},
+				{DNSName: "10.2.0.192.in-addr.arpa", RecordType: "PTR", Targets: []string{"web.example.com"}},
+				// invalid record data is ignored
+				{DNSName: "broken.example.com", RecordType: "MX", Targets: []string{"mx.example.com"}},
+			},
+		},
+	}
+	if err := fakeIndexer.Add(ep); err != nil {
+		t.Fatalf("failed to add DNSEndpoint to indexer: %v", err)
+	}
+
+	gw := newGateway()
+	gw.Zones = []string{"example.com.", "192.in-addr.arpa."}
+	gw.Next = test.NextHandler(dns.RcodeSuccess, nil)
+	gw.ExternalAddrFunc = gw.SelfAddress
+	gw.Controller = &KubeController{hasSynced: true}
+	gw.updateResources([]string{"DNSEndpoint"})
+	gw.lookupResource("DNSEndpoint").lookup = lookupDNSEndpoint(informers{&fakeSharedIndexInformer{indexer: fakeIndexer}})
+
+	ctx := context.TODO()
+	for i, tc := range testsDNSEndpointRecords {
+		r := tc.Msg()
+		w := dnstest.NewRecorder(&test.ResponseWriter{})
+
+		if _, err := gw.ServeDNS(ctx, w, r); err != nil {
+			t.Errorf("Test %d: unexpected error: %v", i, err)
+			continue
+		}
+		if err := test.SortAndCheck(w.Msg, tc); err != nil {
+			t.Errorf("Test %d failed with error: %v", i, err)
+		}
+		if referral := len(w.Msg.Answer) == 0 && len(w.Msg.Ns) > 0 && w.Msg.Ns[0].Header().Rrtype == dns.TypeNS; referral == w.Msg.Authoritative {
+			t.Errorf("Test %d: expected authoritative %v", i, !referral)
+		}
+	}
+
+	result := lookupDNSEndpoint(informers{&fakeSharedIndexInformer{indexer: fakeIndexer}})([]string{"broken.example.com"})
+	if !result.empty() {
+		t.Errorf("expected the invalid MX record to be ignored, got %v", result.records)
+	}
+}
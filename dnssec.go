@@ -0,0 +1,222 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/dnssec"
+	rrcache "github.com/coredns/coredns/plugin/pkg/cache"
+	"github.com/miekg/dns"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// dnssecCacheCapacity is the number of signed RRsets kept, same as the dnssec plugin's default
+const dnssecCacheCapacity = 10000
+
+// dnssecSigner signs answers on the fly with the keys loaded from files and from a
+// Secret. Authenticated denial of existence uses NSEC black lies, see the dnssec plugin.
+type dnssecSigner struct {
+	fileKeys []*dnssec.DNSKEY
+	// secret is the namespace/name of a Secret holding additional keys
+	secret string
+
+	mu      sync.RWMutex
+	handler *dnssec.Dnssec
+}
+
+// current returns the handler signing the answers of gw.serveDNS, nil if DNSSEC
+// isn't configured or no keys are loaded yet.
+func (s *dnssecSigner) current() *dnssec.Dnssec {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.handler
+}
+
+// setKeys replaces the keys used for signing. Cached signatures are dropped.
+func (s *dnssecSigner) setKeys(gw *Gateway, keys []*dnssec.DNSKEY) error {
+	for _, k := range keys {
+		owner := plugin.Name(k.K.Header().Name)
+		if !slices.ContainsFunc(gw.Zones, owner.Matches) {
+			return fmt.Errorf("key %s (keyid: %d) can not sign any of the zones", string(owner), k.K.KeyTag())
+		}
+	}
+
+	var handler *dnssec.Dnssec
+	if len(keys) > 0 {
+		d := dnssec.New(gw.Zones, keys, splitKeys(keys), plugin.HandlerFunc(gw.serveDNS), rrcache.New[[]dns.RR](dnssecCacheCapacity))
+		handler = &d
+	}
+
+	s.mu.Lock()
+	s.handler = handler
+	s.mu.Unlock()
+	return nil
+}
+
+// splitKeys reports whether there are both KSKs and ZSKs, in which case the
+// DNSKEY RRset is signed with the KSKs and everything else with the ZSKs.
+func splitKeys(keys []*dnssec.DNSKEY) bool {
+	var zsk, ksk bool
+	for _, k := range keys {
+		if k.K.Flags&dns.ZONE == 0 {
+			continue
+		}
+		if k.K.Flags&dns.SEP == dns.SEP {
+			ksk = true
+		} else {
+			zsk = true
+		}
+	}
+	return zsk && ksk
+}
+
+// parseKeyFiles reads key pairs as generated by dnssec-keygen. Each base may name
+// the public (.key) or private (.private) file or leave out the extension.
+func parseKeyFiles(bases []string) (keys []*dnssec.DNSKEY, err error) {
+	for _, base := range bases {
+		base = strings.TrimSuffix(strings.TrimSuffix(base, ".key"), ".private")
+		k, err := dnssec.ParseKeyFile(base+".key", base+".private")
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// secretKeys reads the key pairs stored in a Secret. Every pair is stored under the
+// file names generated by dnssec-keygen, e.g. Kexample.com.+013+28484.key and
+// Kexample.com.+013+28484.private. The keys are never written to disk.
+func secretKeys(secret *core.Secret) (keys []*dnssec.DNSKEY, err error) {
+	var bases []string
+	for name := range secret.Data {
+		if base, ok := strings.CutSuffix(name, ".key"); ok {
+			bases = append(bases, base)
+		}
+	}
+	slices.Sort(bases)
+
+	for _, base := range bases {
+		k, err := parseKeyPair(secret.Data[base+".key"], secret.Data[base+".private"])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse key %s of secret %s/%s: %w", base, secret.Namespace, secret.Name, err)
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// parseKeyPair parses the public and private key of a key pair in memory. The
+// dnssec plugin keeps the signer of a key unexported and only builds keys from
+// files, so the checked key pair is written to a private temporary directory that
+// is removed once the key is read.
+func parseKeyPair(public, private []byte) (*dnssec.DNSKEY, error) {
+	rr, err := dns.NewRR(string(public))
+	if err != nil {
+		return nil, err
+	}
+	dk, ok := rr.(*dns.DNSKEY)
+	if !ok {
+		return nil, fmt.Errorf("no public key found")
+	}
+	if len(private) == 0 {
+		return nil, fmt.Errorf("no private key found")
+	}
+	if _, err := dk.NewPrivateKey(string(private)); err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "k8s_gateway-dnssec-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	base := filepath.Join(dir, "key")
+	if err := os.WriteFile(base+".key", public, 0o600); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(base+".private", private, 0o600); err != nil {
+		return nil, err
+	}
+	return dnssec.ParseKeyFile(base+".key", base+".private")
+}
+
+// initializeDNSSECSecretController watches the Secret holding the DNSSEC keys and
+// reloads the keys on every change of it.
+func initializeDNSSECSecretController(ctx context.Context, ctrl *KubeController, gw *Gateway) {
+	if gw.signer == nil || gw.signer.secret == "" {
+		return
+	}
+	namespace, name, _ := strings.Cut(gw.signer.secret, "/")
+
+	secretController := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc:  secretLister(ctx, ctrl.client, namespace, name),
+			WatchFunc: secretWatcher(ctx, ctrl.client, namespace, name),
+		},
+		&core.Secret{},
+		defaultResyncPeriod,
+		cache.Indexers{},
+	)
+
+	update := func(obj interface{}) {
+		secret, ok := obj.(*core.Secret)
+		if !ok {
+			return
+		}
+		keys, err := secretKeys(secret)
+		if err == nil {
+			err = gw.signer.setKeys(gw, append(slices.Clone(gw.signer.fileKeys), keys...))
+		}
+		if err != nil {
+			log.Errorf("failed to load DNSSEC keys: %s", err)
+			return
+		}
+		log.Infof("loaded %d DNSSEC keys from secret %s", len(keys), gw.signer.secret)
+	}
+	_, err := secretController.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    update,
+		UpdateFunc: func(_, obj interface{}) { update(obj) },
+		DeleteFunc: func(interface{}) {
+			log.Warningf("DNSSEC secret %s was deleted, only signing with the keys from files", gw.signer.secret)
+			if err := gw.signer.setKeys(gw, gw.signer.fileKeys); err != nil {
+				log.Errorf("failed to load DNSSEC keys: %s", err)
+			}
+		},
+	})
+	if err != nil {
+		log.Warningf("failed to register DNSSEC secret event handler: %s", err)
+	}
+
+	ctrl.addController("Secret", secretController)
+	log.Infof("DNSSEC secret controller initialized")
+}
+
+func secretLister(ctx context.Context, c kubernetes.Interface, ns, name string) func(metav1.ListOptions) (runtime.Object, error) {
+	return func(opts metav1.ListOptions) (runtime.Object, error) {
+		opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		return c.CoreV1().Secrets(ns).List(ctx, opts)
+	}
+}
+
+func secretWatcher(ctx context.Context, c kubernetes.Interface, ns, name string) func(metav1.ListOptions) (watch.Interface, error) {
+	return func(opts metav1.ListOptions) (watch.Interface, error) {
+		opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		return c.CoreV1().Secrets(ns).Watch(ctx, opts)
+	}
+}
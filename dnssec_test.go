@@ -0,0 +1,203 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// generateKey returns the file names and contents of a new key pair for zone
+func generateKey(t *testing.T, zone string, flags uint16) (base string, files map[string][]byte) {
+	k := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: zone, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     flags,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv, err := k.Generate(256)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	base = fmt.Sprintf("K%s+%03d+%05d", zone, k.Algorithm, k.KeyTag())
+	return base, map[string][]byte{
+		base + ".key":     []byte(k.String() + "\n"),
+		base + ".private": []byte(k.PrivateKeyString(priv)),
+	}
+}
+
+func writeKey(t *testing.T, zone string, flags uint16) string {
+	dir := t.TempDir()
+	base, files := generateKey(t, zone, flags)
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(dir, base)
+}
+
+func TestDNSSECParsing(t *testing.T) {
+	base := writeKey(t, "example.org.", dns.ZONE|dns.SEP)
+	other := writeKey(t, "example.net.", dns.ZONE|dns.SEP)
+
+	tests := []struct {
+		input        string
+		shouldErr    bool
+		expectedKeys int
+		secret       string
+	}{
+		{fmt.Sprintf("k8s_gateway example.org {\n dnssec file %s\n}", base), false, 1, ""},
+		{fmt.Sprintf("k8s_gateway example.org {\n dnssec file %s.key %s.private\n}", base, base), false, 2, ""},
+		{"k8s_gateway example.org {\n dnssec secret kube-system/dnssec-keys\n}", false, 0, "kube-system/dnssec-keys"},
+		{"k8s_gateway example.org {\n dnssec secret dnssec-keys\n}", true, 0, ""},
+		{"k8s_gateway example.org {\n dnssec file\n}", true, 0, ""},
+		{"k8s_gateway example.org {\n dnssec file /does/not/exist\n}", true, 0, ""},
+		{"k8s_gateway example.org {\n dnssec vault path/to/keys\n}", true, 0, ""},
+		// key for a zone that isn't served
+		{fmt.Sprintf("k8s_gateway example.org {\n dnssec file %s\n}", other), true, 0, ""},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		gw, err := parse(c)
+
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error for input %s", i, tc.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error for input %s, got: %v", i, tc.input, err)
+			continue
+		}
+		if len(gw.signer.fileKeys) != tc.expectedKeys {
+			t.Errorf("Test %d: expected %d keys, got %d", i, tc.expectedKeys, len(gw.signer.fileKeys))
+		}
+		if gw.signer.secret != tc.secret {
+			t.Errorf("Test %d: expected secret %q, got %q", i, tc.secret, gw.signer.secret)
+		}
+	}
+}
+
+func TestSecretKeys(t *testing.T) {
+	_, ksk := generateKey(t, "example.com.", dns.ZONE|dns.SEP)
+	_, zsk := generateKey(t, "example.com.", dns.ZONE)
+	secret := &core.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "dnssec-keys", Namespace: "kube-system"},
+		Data:       map[string][]byte{},
+	}
+	for name, data := range ksk {
+		secret.Data[name] = data
+	}
+	for name, data := range zsk {
+		secret.Data[name] = data
+	}
+
+	keys, err := secretKeys(secret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 keys, got %d", len(keys))
+	}
+	if !splitKeys(keys) {
+		t.Errorf("expected a KSK and a ZSK to split keys")
+	}
+
+	for name := range zsk {
+		delete(secret.Data, name)
+	}
+	for name := range ksk {
+		secret.Data[name] = []byte("garbage")
+		break
+	}
+	if _, err := secretKeys(secret); err == nil {
+		t.Errorf("expected an error for an invalid key")
+	}
+
+	// a public key without its private key
+	secret.Data = map[string][]byte{}
+	for name, data := range ksk {
+		if filepath.Ext(name) == ".key" {
+			secret.Data[name] = data
+		}
+	}
+	if _, err := secretKeys(secret); err == nil {
+		t.Errorf("expected an error for a missing private key")
+	}
+}
+
+func TestPluginDNSSEC(t *testing.T) {
+	gw := newGateway()
+	gw.Zones = []string{"example.com."}
+	gw.Next = test.NextHandler(dns.RcodeSuccess, nil)
+	gw.ExternalAddrFunc = gw.SelfAddress
+	gw.Controller = &KubeController{hasSynced: true}
+	setupLookupFuncs(gw)
+
+	keys, err := parseKeyFiles([]string{writeKey(t, "example.com.", dns.ZONE|dns.SEP)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw.signer = &dnssecSigner{}
+	if err := gw.signer.setKeys(gw, keys); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		qname     string
+		qtype     uint16
+		do        bool
+		answer    []uint16
+		authority []uint16
+	}{
+		// signed answer
+		{"domain.example.com.", dns.TypeA, true, []uint16{dns.TypeA, dns.TypeRRSIG}, nil},
+		// not signed without the DO bit
+		{"domain.example.com.", dns.TypeA, false, []uint16{dns.TypeA}, nil},
+		// keys at the apex
+		{"example.com.", dns.TypeDNSKEY, true, []uint16{dns.TypeDNSKEY, dns.TypeRRSIG}, nil},
+		// black lie for a name that doesn't exist
+		{"nonexistent.example.com.", dns.TypeA, true, nil, []uint16{dns.TypeSOA, dns.TypeRRSIG, dns.TypeRRSIG, dns.TypeNSEC}},
+		// nodata
+		{"domain.example.com.", dns.TypeAAAA, true, nil, []uint16{dns.TypeSOA, dns.TypeRRSIG, dns.TypeRRSIG, dns.TypeNSEC}},
+	}
+
+	ctx := context.TODO()
+	for i, tc := range tests {
+		r := test.Case{Qname: tc.qname, Qtype: tc.qtype, Do: tc.do}.Msg()
+		w := dnstest.NewRecorder(&test.ResponseWriter{})
+
+		if _, err := gw.ServeDNS(ctx, w, r); err != nil {
+			t.Errorf("Test %d: unexpected error: %v", i, err)
+			continue
+		}
+		if w.Msg.Rcode != dns.RcodeSuccess {
+			t.Errorf("Test %d: expected NOERROR, got %s", i, dns.RcodeToString[w.Msg.Rcode])
+		}
+		if got := rrTypes(w.Msg.Answer); !slices.Equal(got, tc.answer) {
+			t.Errorf("Test %d: expected answer types %v, got %v", i, tc.answer, got)
+		}
+		if got := rrTypes(w.Msg.Ns); !slices.Equal(got, tc.authority) {
+			t.Errorf("Test %d: expected authority types %v, got %v", i, tc.authority, got)
+		}
+	}
+}
+
+func rrTypes(rrs []dns.RR) (types []uint16) {
+	for _, rr := range rrs {
+		types = append(types, rr.Header().Rrtype)
+	}
+	return types
+}
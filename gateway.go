@@ -2,36 +2,143 @@ package gateway
 
 import (
 	"context"
-	"fmt"
 	"net"
 	"net/netip"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
 	"github.com/coredns/coredns/plugin/pkg/fall"
+	"github.com/coredns/coredns/plugin/transfer"
 	"github.com/coredns/coredns/request"
 	"github.com/miekg/dns"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-type lookupFunc func(indexKeys []string) (results []netip.Addr, raws []string)
+// lookupResult holds everything a resource returned for a set of index keys.
+// Hostnames are the load balancer hostnames reported in object statuses; they
+// are either resolved to addresses or answered with a CNAME, see cnameHostnames.
+type lookupResult struct {
+	addrs     []netip.Addr
+	raws      []string
+	hostnames []string
+	ports     []servicePort
+	// records holds any other record published for the name, e.g. MX, with an
+	// empty owner name and TTL, which are set when answering.
+	records []dns.RR
+	// alias marks hostnames that are always answered with a CNAME, never resolved
+	alias bool
+	// ttl is the lowest TTL set on any of the matching objects, 0 if none is set.
+	// All records of a name form one RRset per type and must share a TTL.
+	ttl uint32
+	// revision is the highest resourceVersion of the objects the result was built
+	// from, which the SOA serial is derived from, see zoneSerial.
+	revision uint64
+}
+
+// servicePort is a named port published for a hostname, answered with SRV records.
+type servicePort struct {
+	name     string
+	protocol string
+	port     uint16
+}
+
+func (r lookupResult) empty() bool {
+	return len(r.addrs) == 0 && len(r.raws) == 0 && len(r.hostnames) == 0 && len(r.records) == 0
+}
+
+// merge returns r with all records of other appended to it
+func (r lookupResult) merge(other lookupResult) lookupResult {
+	r.addrs = append(r.addrs, other.addrs...)
+	r.raws = append(r.raws, other.raws...)
+	r.hostnames = append(r.hostnames, other.hostnames...)
+	r.ports = append(r.ports, other.ports...)
+	r.records = append(r.records, other.records...)
+	r.alias = r.alias || other.alias
+	r.lowerTTL(other.ttl)
+	r.revision = max(r.revision, other.revision)
+	return r
+}
+
+// recordsOfType returns the records of the given type
+func (r lookupResult) recordsOfType(rrtype uint16) (records []dns.RR) {
+	for _, rr := range r.records {
+		if rr.Header().Rrtype == rrtype {
+			records = append(records, rr)
+		}
+	}
+	return records
+}
+
+// lowerTTL sets the TTL of r to ttl if it's lower than the current one. A ttl of 0 is ignored.
+func (r *lookupResult) lowerTTL(ttl uint32) {
+	if ttl != 0 && (r.ttl == 0 || ttl < r.ttl) {
+		r.ttl = ttl
+	}
+}
+
+// observe records the resourceVersion of an object the result is built from
+func (r *lookupResult) observe(obj metav1.Object) {
+	if version, err := strconv.ParseUint(obj.GetResourceVersion(), 10, 64); err == nil {
+		r.revision = max(r.revision, version)
+	}
+}
+
+// observeAll records the resourceVersions of objects taken from an informer
+func (r *lookupResult) observeAll(objs []interface{}) {
+	for _, obj := range objs {
+		if o, ok := obj.(metav1.Object); ok {
+			r.observe(o)
+		}
+	}
+}
+
+// matchingPorts returns the ports of r with the given name and protocol
+func (r lookupResult) matchingPorts(name, protocol string) (ports []servicePort) {
+	for _, port := range r.ports {
+		if strings.EqualFold(port.name, name) && strings.EqualFold(port.protocol, protocol) {
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
+
+type lookupFunc func(indexKeys []string) lookupResult
 
 type resourceWithIndex struct {
 	name   string
 	lookup lookupFunc
+	keys   func() []string
 }
 
-// Static resources with their default noop function
+// Static resources with their default noop functions
 var staticResources = []*resourceWithIndex{
-	{name: "HTTPRoute", lookup: noop},
-	{name: "TLSRoute", lookup: noop},
-	{name: "GRPCRoute", lookup: noop},
-	{name: "Ingress", lookup: noop},
-	{name: "Service", lookup: noop},
-	{name: "DNSEndpoint", lookup: noop},
-	{name: "Node", lookup: noop},
+	{name: "HTTPRoute", lookup: noop, keys: noKeys},
+	{name: "TLSRoute", lookup: noop, keys: noKeys},
+	{name: "GRPCRoute", lookup: noop, keys: noKeys},
+	{name: "TCPRoute", lookup: noop, keys: noKeys},
+	{name: "UDPRoute", lookup: noop, keys: noKeys},
+	{name: "Gateway", lookup: noop, keys: noKeys},
+	{name: "VirtualService", lookup: noop, keys: noKeys},
+	{name: "IstioGateway", lookup: noop, keys: noKeys},
+	{name: "Ingress", lookup: noop, keys: noKeys},
+	{name: "IngressRoute", lookup: noop, keys: noKeys},
+	{name: "IngressRouteTCP", lookup: noop, keys: noKeys},
+	{name: "HTTPProxy", lookup: noop, keys: noKeys},
+	{name: "Route", lookup: noop, keys: noKeys},
+	{name: "Service", lookup: noop, keys: noKeys},
+	{name: "ServiceImport", lookup: noop, keys: noKeys},
+	{name: "Pod", lookup: noop, keys: noKeys},
+	{name: "DNSEndpoint", lookup: noop, keys: noKeys},
+	{name: "Node", lookup: noop, keys: noKeys},
 }
 
-var noop lookupFunc = func([]string) (result []netip.Addr, raws []string) { return }
+var noop lookupFunc = func([]string) (result lookupResult) { return }
+
+var noKeys = func() []string { return nil }
 
 var (
 	ttlDefault        = uint32(60)
@@ -41,6 +148,9 @@ var (
 	defaultSecondNS   = ""
 )
 
+// maxCNAMEChain bounds how many in-zone CNAMEs are followed for a single answer
+const maxCNAMEChain = 8
+
 // Gateway stores all runtime configuration of a plugin
 type Gateway struct {
 	Next                plugin.Handler
@@ -56,16 +166,31 @@ type Gateway struct {
 	configFile          string
 	configContext       string
 	nodeAddressType     string
+	cnameHostnames      bool
 	ExternalAddrFunc    func(request.Request) []dns.RR
 	resourceFilters     ResourceFilters
+	reverse             *reverseIndex
+	history             *zoneHistory
+	changes             chan struct{}
+	signer              *dnssecSigner
 
 	Fall fall.F
+	Xfer *transfer.Transfer
 }
 
 type ResourceFilters struct {
 	ingressClasses        []string
 	gatewayClasses        []string
 	serviceLabelSelectors []string
+	// namespaces and namespaceLabelSelector restrict the informers to the listed
+	// namespaces and the namespaces matching the selector. Empty watches all namespaces.
+	namespaces             []string
+	namespaceLabelSelector string
+	// routeStatus only publishes Gateway API routes accepted by a programmed parent
+	routeStatus bool
+	// traefikServices are the namespace/name of the Services exposing Traefik, whose
+	// addresses IngressRoutes are published with
+	traefikServices []string
 }
 
 // Create a new Gateway instance
@@ -86,6 +211,9 @@ func newGateway() *Gateway {
 		secondNS:            defaultSecondNS,
 		hostmaster:          defaultHostmaster,
 		nodeAddressType:     "InternalIP",
+		reverse:             newReverseIndex(),
+		history:             newZoneHistory(),
+		changes:             make(chan struct{}, 1),
 	}
 }
 
@@ -134,6 +262,14 @@ func (gw *Gateway) SetConfiguredResources(newResources []string) {
 
 // ServeDNS implements the plugin.Handle interface.
 func (gw *Gateway) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	if signer := gw.signer.current(); signer != nil {
+		return signer.ServeDNS(ctx, w, r)
+	}
+	return gw.serveDNS(ctx, w, r)
+}
+
+// serveDNS answers the query from the watched resources
+func (gw *Gateway) serveDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
 	state := request.Request{W: w, Req: r}
 	//log.Infof("Incoming query %s", state.QName())
 
@@ -150,8 +286,11 @@ func (gw *Gateway) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Ms
 	log.Debugf("computed Index Keys sets %v", indexKeySets)
 
 	if !gw.Controller.HasSynced() {
-		// TODO maybe there's a better way to do this? e.g. return an error back to the client?
-		return dns.RcodeServerFailure, plugin.Error(thisPlugin, fmt.Errorf("could not sync required resources"))
+		return gw.serveNotSynced(state)
+	}
+
+	if isReverseZone(zone) {
+		return gw.serveReverse(ctx, state)
 	}
 
 	var isRootZoneQuery bool
@@ -167,22 +306,40 @@ func (gw *Gateway) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Ms
 		}
 	}
 
-	addrs, raws := gw.getMatchingAddresses(indexKeySets)
-	log.Debugf("computed response addresses %v", addrs)
-	log.Debugf("computed response raws %v", raws)
+	// Names at or below a zone cut are answered with a referral, except for the DS
+	// records of the cut itself which belong to the parent zone
+	if ns := gw.delegation(state.Name(), zone); len(ns) > 0 {
+		if state.QType() != dns.TypeDS || !strings.EqualFold(ns[0].Header().Name, state.Name()) {
+			return gw.serveReferral(state, ns)
+		}
+	}
+
+	result := gw.queryMatchingAddresses(indexKeySets)
+	log.Debugf("computed response addresses %v", result.addrs)
+	log.Debugf("computed response raws %v", result.raws)
+	log.Debugf("computed response hostnames %v", result.hostnames)
+
+	// Names that don't exist on their own may still be the SRV name of a port
+	if result.empty() {
+		if srv, ok := parseSRVQueryName(qname, zone); ok {
+			return gw.serveSRV(ctx, state, srv)
+		}
+	}
 
 	// Fall through if no host matches
-	if len(addrs) == 0 && len(raws) == 0 && gw.Fall.Through(qname) {
+	if result.empty() && gw.Fall.Through(qname) {
+		fallthroughCount.WithLabelValues(metrics.WithServer(ctx)).Inc()
 		return plugin.NextOrFailure(gw.Name(), gw.Next, ctx, w, r)
 	}
 
 	m := new(dns.Msg)
 	m.SetReply(state.Req)
+	ttl := gw.recordTTL(result)
 
 	var ipv4Addrs []netip.Addr
 	var ipv6Addrs []netip.Addr
 
-	for _, addr := range addrs {
+	for _, addr := range result.addrs {
 		if addr.Is4() {
 			ipv4Addrs = append(ipv4Addrs, addr)
 		}
@@ -191,59 +348,60 @@ func (gw *Gateway) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Ms
 		}
 	}
 
-	switch state.QType() {
-	case dns.TypeA:
-
-		if len(ipv4Addrs) == 0 {
+	// A name backed by a hostname only holds a CNAME, so queries of any type
+	// are answered with it.
+	qtype := state.QType()
+	if len(result.hostnames) > 0 {
+		qtype = dns.TypeCNAME
+	}
 
-			if !isRootZoneQuery {
-				// No match, return NXDOMAIN
-				m.Rcode = dns.RcodeNameError
-			}
+	// A name holding records of other types than the queried one exists, so the
+	// answer is NODATA rather than NXDOMAIN
+	noData := func() {
+		if result.empty() && !isRootZoneQuery {
+			m.Rcode = dns.RcodeNameError
+		}
+		m.Ns = []dns.RR{gw.soa(state)}
+	}
 
-			// as per rfc4074 #3 (symmetric: IPv6-only record yields NODATA for A queries)
-			if len(ipv6Addrs) > 0 {
-				m.Rcode = dns.RcodeSuccess
-			}
+	switch qtype {
+	case dns.TypeCNAME:
 
+		if len(result.hostnames) == 0 {
+			// CNAME queries are answered with NODATA like they always have been
 			m.Ns = []dns.RR{gw.soa(state)}
+			break
+		}
+
+		m.Answer = gw.CNAME(state.Name(), ttl, result.hostnames)
+		if state.QType() == dns.TypeA || state.QType() == dns.TypeAAAA {
+			target := m.Answer[0].(*dns.CNAME).Target
+			m.Answer = append(m.Answer, gw.chaseCNAME(target, state.QType())...)
+		}
+	case dns.TypeA:
 
+		if len(ipv4Addrs) == 0 {
+			// as per rfc4074 #3 (symmetric: IPv6-only record yields NODATA for A queries)
+			noData()
 		} else {
 
-			m.Answer = gw.A(state.Name(), ipv4Addrs)
+			m.Answer = gw.A(state.Name(), ttl, ipv4Addrs)
 		}
 	case dns.TypeAAAA:
 
 		if len(ipv6Addrs) == 0 {
-
-			if !isRootZoneQuery {
-				// No match, return NXDOMAIN
-				m.Rcode = dns.RcodeNameError
-			}
-
 			// as per rfc4074 #3
-			if len(ipv4Addrs) > 0 {
-				m.Rcode = dns.RcodeSuccess
-			}
-
-			m.Ns = []dns.RR{gw.soa(state)}
-
+			noData()
 		} else {
 
-			m.Answer = gw.AAAA(state.Name(), ipv6Addrs)
+			m.Answer = gw.AAAA(state.Name(), ttl, ipv6Addrs)
 		}
 	case dns.TypeTXT:
 
-		if len(raws) == 0 {
-
-			if !isRootZoneQuery {
-				// No match, return NXDOMAIN
-				m.Rcode = dns.RcodeNameError
-			}
-
-			m.Ns = []dns.RR{gw.soa(state)}
+		if len(result.raws) == 0 {
+			noData()
 		} else {
-			m.Answer = gw.TXT(state.Name(), raws)
+			m.Answer = gw.TXT(state.Name(), ttl, result.raws)
 		}
 	case dns.TypeSOA:
 
@@ -260,11 +418,15 @@ func (gw *Gateway) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Ms
 				m.Extra = append(m.Extra, rr)
 			}
 		} else {
-			m.Ns = []dns.RR{gw.soa(state)}
+			noData()
 		}
 
 	default:
-		m.Ns = []dns.RR{gw.soa(state)}
+		if records := result.recordsOfType(qtype); len(records) > 0 {
+			m.Answer = gw.records(state.Name(), ttl, records)
+			break
+		}
+		noData()
 	}
 
 	// Force to true to fix broken behaviour of legacy glibc `getaddrinfo`.
@@ -278,6 +440,100 @@ func (gw *Gateway) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Ms
 	return dns.RcodeSuccess, nil
 }
 
+// serveSRV answers queries for the SRV name of a port with the ports published
+// for its hostname. The targets' addresses are added to the additional section.
+func (gw *Gateway) serveSRV(ctx context.Context, state request.Request, srv srvQuery) (int, error) {
+	result, ports := gw.getMatchingPorts(gw.getQueryIndexKeySets(srv.hostname, state.Zone), srv)
+	log.Debugf("computed response ports %v", ports)
+
+	if len(ports) == 0 && gw.Fall.Through(state.Name()) {
+		fallthroughCount.WithLabelValues(metrics.WithServer(ctx)).Inc()
+		return plugin.NextOrFailure(gw.Name(), gw.Next, ctx, state.W, state.Req)
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(state.Req)
+	m.Authoritative = true
+
+	switch {
+	case len(ports) == 0:
+		m.Rcode = dns.RcodeNameError
+		m.Ns = []dns.RR{gw.soa(state)}
+	case state.QType() == dns.TypeSRV:
+		ttl := gw.recordTTL(result)
+		if len(result.hostnames) > 0 {
+			target, extra := gw.srvTarget(result.hostnames)
+			m.Answer = gw.SRV(state.Name(), target, ttl, ports)
+			m.Extra = extra
+			break
+		}
+		m.Answer = gw.SRV(state.Name(), srv.hostname, ttl, ports)
+		var ipv4Addrs, ipv6Addrs []netip.Addr
+		for _, addr := range result.addrs {
+			if addr.Is4() {
+				ipv4Addrs = append(ipv4Addrs, addr)
+			}
+			if addr.Is6() {
+				ipv6Addrs = append(ipv6Addrs, addr)
+			}
+		}
+		m.Extra = append(gw.A(srv.hostname, ttl, ipv4Addrs), gw.AAAA(srv.hostname, ttl, ipv6Addrs)...)
+	default:
+		m.Ns = []dns.RR{gw.soa(state)}
+	}
+
+	if err := state.W.WriteMsg(m); err != nil {
+		log.Errorf("failed to send a response: %s", err)
+	}
+
+	return dns.RcodeSuccess, nil
+}
+
+// srvQuery is a query name of the form _<port-name>._<proto>.<hostname>
+type srvQuery struct {
+	portName string
+	protocol string
+	hostname string
+}
+
+// parseSRVQueryName splits an SRV query name into the port name, the protocol
+// and the hostname the port is published for.
+func parseSRVQueryName(qName, zone string) (srvQuery, bool) {
+	labels := dns.SplitDomainName(stripDomain(qName, zone))
+	if len(labels) < 2 {
+		return srvQuery{}, false
+	}
+	for _, label := range labels[:2] {
+		if len(label) < 2 || label[0] != '_' {
+			return srvQuery{}, false
+		}
+	}
+
+	return srvQuery{
+		portName: labels[0][1:],
+		protocol: labels[1][1:],
+		hostname: qName[len(labels[0])+len(labels[1])+2:],
+	}, true
+}
+
+// Gets the first published hostname that has ports matching the SRV query,
+// together with those ports.
+func (gw *Gateway) getMatchingPorts(indexKeySets [][]string, srv srvQuery) (lookupResult, []servicePort) {
+	for _, indexKeys := range indexKeySets {
+		for _, resource := range gw.Resources {
+			result := gw.resolveHostnames(resource.lookup(indexKeys))
+			if result.empty() {
+				continue
+			}
+			if ports := result.matchingPorts(srv.portName, srv.protocol); len(ports) > 0 {
+				return result, ports
+			}
+		}
+	}
+
+	return lookupResult{}, nil
+}
+
 // Computes keys to look up in cache
 func (gw *Gateway) getQueryIndexKeys(qName, zone string) []string {
 	zonelessQuery := stripDomain(qName, zone)
@@ -327,54 +583,231 @@ func (gw *Gateway) toWildcardQName(qName, zone string) string {
 }
 
 // Gets the set of addresses associated with the first set of index keys
-// that is in the indexer.
-func (gw *Gateway) getMatchingAddresses(indexKeySets [][]string) ([]netip.Addr, []string) {
+// that is in the indexer. It is used for the names looked up while answering a
+// query, e.g. CNAME targets, and records no lookup metrics.
+func (gw *Gateway) getMatchingAddresses(indexKeySets [][]string) lookupResult {
+	return gw.matchingResult(indexKeySets, gw.resolveHostnames, nil)
+}
+
+// queryMatchingAddresses is getMatchingAddresses for the name of a client query,
+// recording the lookups in the lookup metrics.
+func (gw *Gateway) queryMatchingAddresses(indexKeySets [][]string) lookupResult {
+	return gw.matchingResult(indexKeySets, gw.resolveHostnames, observeLookup)
+}
+
+// matchingResult returns the result of the first resource matching the first set
+// of index keys that is in the indexer. resolve is applied to the result of every
+// resource before it is checked, observe is called for every resource looked up
+// if set.
+func (gw *Gateway) matchingResult(indexKeySets [][]string, resolve func(lookupResult) lookupResult, observe func(resource string, start time.Time, hit bool)) lookupResult {
 	// Iterate over supported resources and lookup DNS queries
 	// Stop once we've found at least one match
 	for _, indexKeys := range indexKeySets {
 		for _, resource := range gw.Resources {
-			addrs, raws := resource.lookup(indexKeys)
-			if len(addrs) > 0 || len(raws) > 0 {
-				return addrs, raws
+			start := time.Now()
+			result := resolve(resource.lookup(indexKeys))
+			if observe != nil {
+				observe(resource.name, start, !result.empty())
+			}
+			if !result.empty() {
+				return result
 			}
 		}
 	}
 
-	return nil, nil
+	return lookupResult{}
+}
+
+// resolveHostnames replaces the load balancer hostnames of a result with the
+// addresses they currently resolve to. Hostnames are kept as they are when they
+// are to be answered with a CNAME, which is only possible when the result holds
+// no addresses of its own.
+func (gw *Gateway) resolveHostnames(result lookupResult) lookupResult {
+	if len(result.hostnames) == 0 || ((gw.cnameHostnames || result.alias) && len(result.addrs) == 0) {
+		return result
+	}
+	for _, hostname := range result.hostnames {
+		result.addrs = append(result.addrs, lookupHostname(hostname)...)
+	}
+	result.hostnames = nil
+	return result
+}
+
+// chaseCNAME follows a CNAME target that is inside one of the configured zones
+// and returns the records answering qtype for it, so that clients don't need
+// another round trip. Targets outside of our zones are left to the resolver.
+func (gw *Gateway) chaseCNAME(target string, qtype uint16) (records []dns.RR) {
+	for range maxCNAMEChain {
+		zone := plugin.Zones(gw.Zones).Matches(target)
+		if zone == "" {
+			return records
+		}
+
+		result := gw.getMatchingAddresses(gw.getQueryIndexKeySets(target, zone))
+		if len(result.hostnames) > 0 {
+			cname := gw.CNAME(target, gw.recordTTL(result), result.hostnames)
+			records = append(records, cname...)
+			target = cname[0].(*dns.CNAME).Target
+			continue
+		}
+
+		var addrs []netip.Addr
+		for _, addr := range result.addrs {
+			if (qtype == dns.TypeA && addr.Is4()) || (qtype == dns.TypeAAAA && addr.Is6()) {
+				addrs = append(addrs, addr)
+			}
+		}
+		if qtype == dns.TypeA {
+			return append(records, gw.A(target, gw.recordTTL(result), addrs)...)
+		}
+		return append(records, gw.AAAA(target, gw.recordTTL(result), addrs)...)
+	}
+
+	log.Warningf("CNAME chain for %s exceeds %d records, not following it", target, maxCNAMEChain)
+	return records
 }
 
 // Name implements the Handler interface.
 func (gw *Gateway) Name() string { return thisPlugin }
 
+// Ready implements the ready.Readiness interface, it reports whether all resources have synced.
+func (gw *Gateway) Ready() bool { return gw.Controller != nil && gw.Controller.HasSynced() }
+
+// serveNotSynced answers with SERVFAIL while the resources are still syncing. The
+// reason is sent as an Extended DNS Error (RFC 8914) if the client supports EDNS.
+func (gw *Gateway) serveNotSynced(state request.Request) (int, error) {
+	unsynced := gw.Controller.unsynced()
+	log.Debugf("not answering %s, resources not synced: %v", state.Name(), unsynced)
+
+	m := new(dns.Msg)
+	m.SetRcode(state.Req, dns.RcodeServerFailure)
+	if state.Req.IsEdns0() != nil {
+		m.SetEdns0(uint16(state.Size()), state.Do())
+		ede := dns.EDNS0_EDE{InfoCode: dns.ExtendedErrorCodeNotReady, ExtraText: "k8s_gateway: resources not synced"}
+		if len(unsynced) > 0 {
+			ede.ExtraText += ": " + strings.Join(unsynced, ", ")
+		}
+		m.IsEdns0().Option = append(m.IsEdns0().Option, &ede)
+	}
+
+	if err := state.W.WriteMsg(m); err != nil {
+		log.Errorf("failed to send a response: %s", err)
+	}
+	return dns.RcodeSuccess, nil
+}
+
+// recordTTL returns the TTL to answer a result with, the one set on the matching
+// objects or the configured ttl.
+func (gw *Gateway) recordTTL(result lookupResult) uint32 {
+	if result.ttl != 0 {
+		return result.ttl
+	}
+	return gw.ttlLow
+}
+
 // A does the A-record lookup in ingress indexer
-func (gw *Gateway) A(name string, results []netip.Addr) (records []dns.RR) {
+func (gw *Gateway) A(name string, ttl uint32, results []netip.Addr) (records []dns.RR) {
 	dup := make(map[string]struct{})
 	for _, result := range results {
 		if _, ok := dup[result.String()]; !ok {
 			dup[result.String()] = struct{}{}
-			records = append(records, &dns.A{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: gw.ttlLow}, A: net.ParseIP(result.String())})
+			records = append(records, &dns.A{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: ttl}, A: net.ParseIP(result.String())})
 		}
 	}
 	return records
 }
 
-func (gw *Gateway) AAAA(name string, results []netip.Addr) (records []dns.RR) {
+func (gw *Gateway) AAAA(name string, ttl uint32, results []netip.Addr) (records []dns.RR) {
 	dup := make(map[string]struct{})
 	for _, result := range results {
 		if _, ok := dup[result.String()]; !ok {
 			dup[result.String()] = struct{}{}
-			records = append(records, &dns.AAAA{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: gw.ttlLow}, AAAA: net.ParseIP(result.String())})
+			records = append(records, &dns.AAAA{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: ttl}, AAAA: net.ParseIP(result.String())})
+		}
+	}
+	return records
+}
+
+// srvTarget returns the target of SRV records for a name answered with a CNAME to
+// one of hostnames. SRV targets must not be aliases (RFC 2782), so the CNAME is
+// followed through in-zone names to the one holding the addresses, which are
+// returned for the additional section. Targets outside of our zones are left to
+// the resolver, like in chaseCNAME.
+func (gw *Gateway) srvTarget(hostnames []string) (target string, extra []dns.RR) {
+	target = cnameTarget(hostnames)
+	for range maxCNAMEChain {
+		zone := plugin.Zones(gw.Zones).Matches(target)
+		if zone == "" {
+			return target, nil
+		}
+
+		result := gw.getMatchingAddresses(gw.getQueryIndexKeySets(target, zone))
+		if len(result.hostnames) > 0 {
+			target = cnameTarget(result.hostnames)
+			continue
+		}
+
+		var ipv4Addrs, ipv6Addrs []netip.Addr
+		for _, addr := range result.addrs {
+			if addr.Is4() {
+				ipv4Addrs = append(ipv4Addrs, addr)
+			}
+			if addr.Is6() {
+				ipv6Addrs = append(ipv6Addrs, addr)
+			}
+		}
+		ttl := gw.recordTTL(result)
+		return target, append(gw.A(target, ttl, ipv4Addrs), gw.AAAA(target, ttl, ipv6Addrs)...)
+	}
+	return target, nil
+}
+
+// cnameTarget returns the lowest of hostnames as the target of a CNAME, see CNAME
+func cnameTarget(hostnames []string) string {
+	return dns.Fqdn(strings.ToLower(slices.Min(hostnames)))
+}
+
+// CNAME returns a single CNAME record for name. A name can only hold one CNAME,
+// so when several hostnames match, the lowest one is picked to keep answers stable.
+func (gw *Gateway) CNAME(name string, ttl uint32, hostnames []string) []dns.RR {
+	target := cnameTarget(hostnames)
+	if len(hostnames) > 1 {
+		log.Debugf("multiple hostnames %v found for %s, answering with %s", hostnames, name, target)
+	}
+	return []dns.RR{&dns.CNAME{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: ttl}, Target: target}}
+}
+
+// SRV returns a record per port, all pointing at target
+func (gw *Gateway) SRV(name, target string, ttl uint32, ports []servicePort) (records []dns.RR) {
+	dup := make(map[uint16]struct{})
+	for _, port := range ports {
+		if _, ok := dup[port.port]; !ok {
+			dup[port.port] = struct{}{}
+			records = append(records, &dns.SRV{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: ttl}, Priority: 0, Weight: 100, Port: port.port, Target: target})
 		}
 	}
 	return records
 }
 
-func (gw *Gateway) TXT(name string, results []string) (records []dns.RR) {
+// records returns copies of records owned by name, see lookupResult.records
+func (gw *Gateway) records(name string, ttl uint32, records []dns.RR) (answer []dns.RR) {
+	for _, rr := range records {
+		rr = dns.Copy(rr)
+		rr.Header().Name = name
+		rr.Header().Ttl = ttl
+		if !slices.ContainsFunc(answer, func(a dns.RR) bool { return dns.IsDuplicate(a, rr) }) {
+			answer = append(answer, rr)
+		}
+	}
+	return answer
+}
+
+func (gw *Gateway) TXT(name string, ttl uint32, results []string) (records []dns.RR) {
 	dup := make(map[string]struct{})
 	for _, result := range results {
 		if _, ok := dup[result]; !ok {
 			dup[result] = struct{}{}
-			records = append(records, &dns.TXT{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: gw.ttlLow}, Txt: split255(result)})
+			records = append(records, &dns.TXT{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: ttl}, Txt: split255(result)})
 		}
 	}
 
@@ -386,22 +819,23 @@ func (gw *Gateway) SelfAddress(state request.Request) (records []dns.RR) {
 
 	var addrs1, addrs2 []netip.Addr
 	for _, resource := range gw.Resources {
-		results, raws := resource.lookup([]string{gw.apex})
-		_ = raws
-		if len(results) > 0 {
-			addrs1 = append(addrs1, results...)
+		// glue records must be addresses, so hostnames are always resolved here
+		result := resource.lookup([]string{gw.apex})
+		addrs1 = append(addrs1, result.addrs...)
+		for _, hostname := range result.hostnames {
+			addrs1 = append(addrs1, lookupHostname(hostname)...)
 		}
-		results, raws = resource.lookup([]string{gw.secondNS})
-		_ = raws
-		if len(results) > 0 {
-			addrs2 = append(addrs2, results...)
+		result = resource.lookup([]string{gw.secondNS})
+		addrs2 = append(addrs2, result.addrs...)
+		for _, hostname := range result.hostnames {
+			addrs2 = append(addrs2, lookupHostname(hostname)...)
 		}
 	}
 
-	records = append(records, gw.A(gw.apex+"."+state.Zone, addrs1)...)
+	records = append(records, gw.A(gw.apex+"."+state.Zone, gw.ttlLow, addrs1)...)
 
 	if state.QType() == dns.TypeNS {
-		records = append(records, gw.A(gw.secondNS+"."+state.Zone, addrs2)...)
+		records = append(records, gw.A(gw.secondNS+"."+state.Zone, gw.ttlLow, addrs2)...)
 	}
 
 	return records
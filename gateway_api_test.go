@@ -3,6 +3,7 @@ package gateway
 import (
 	"context"
 	"net/netip"
+	"slices"
 	"strings"
 	"testing"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/miekg/dns"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
 	gatewayapi_v1 "sigs.k8s.io/gateway-api/apis/v1"
 	gatewayapi_v1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
 	gatewayClient "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned"
@@ -26,8 +28,8 @@ func TestGatewayAPILookup(t *testing.T) {
 	gw.Next = test.NextHandler(dns.RcodeSuccess, nil)
 	gw.ExternalAddrFunc = gw.SelfAddress
 	gw.Controller = ctrl
-	real := []string{"HTTPRoute", "TLSRoute", "GRPCRoute"}
-	fake := []string{"Pod", "Gateway"}
+	real := []string{"HTTPRoute", "TLSRoute", "GRPCRoute", "TCPRoute", "UDPRoute", "Gateway"}
+	fake := []string{"ConfigMap"}
 
 	for _, resource := range real {
 		if found := gw.lookupResource(resource); found == nil {
@@ -220,11 +222,11 @@ var testGatewayAPIRouteIndexes = map[string][]netip.Addr{
 	"shadow.example.com":    {netip.MustParseAddr("192.0.2.4")},
 }
 
-func testGatewayAPIRouteLookup(keys []string) (results []netip.Addr, raws []string) {
+func testGatewayAPIRouteLookup(keys []string) (result lookupResult) {
 	for _, key := range keys {
-		results = append(results, testGatewayAPIRouteIndexes[strings.ToLower(key)]...)
+		result.addrs = append(result.addrs, testGatewayAPIRouteIndexes[strings.ToLower(key)]...)
 	}
-	return results, raws
+	return result
 }
 
 func setupGatewayAPILookupFuncs(gw *Gateway) {
@@ -353,3 +355,342 @@ var testGRPCRoutesLegacy = map[string]*gatewayapi_v1alpha2.GRPCRoute{
 		},
 	},
 }
+
+func TestHTTPSRecords(t *testing.T) {
+	gateway := &gatewayapi_v1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "gw-1", Namespace: "ns1"},
+		Spec: gatewayapi_v1.GatewaySpec{
+			Listeners: []gatewayapi_v1.Listener{
+				{Name: "http", Protocol: gatewayapi_v1.HTTPProtocolType, Port: 80},
+				{Name: "https", Protocol: gatewayapi_v1.HTTPSProtocolType, Port: 443},
+				{Name: "https-alt", Protocol: gatewayapi_v1.HTTPSProtocolType, Port: 8443},
+				{Name: "https-alt-2", Protocol: gatewayapi_v1.HTTPSProtocolType, Port: 8443},
+				{Name: "tls", Protocol: gatewayapi_v1.TLSProtocolType, Port: 9443},
+			},
+		},
+	}
+	addrs := []netip.Addr{netip.MustParseAddr("192.0.2.100"), netip.MustParseAddr("2001:db8::100")}
+
+	tests := []struct {
+		service  routeService
+		http3    bool
+		expected []string
+	}{
+		{httpRouteService, false, []string{
+			`.	0	IN	HTTPS	1 . alpn="h2,http/1.1" ipv4hint="192.0.2.100" ipv6hint="2001:db8::100"`,
+			`.	0	IN	HTTPS	1 . alpn="h2,http/1.1" port="8443" ipv4hint="192.0.2.100" ipv6hint="2001:db8::100"`,
+		}},
+		// only the listener on 443 serves HTTP/3
+		{httpRouteService, true, []string{
+			`.	0	IN	HTTPS	1 . alpn="h3,h2,http/1.1" ipv4hint="192.0.2.100" ipv6hint="2001:db8::100"`,
+			`.	0	IN	HTTPS	1 . alpn="h2,http/1.1" port="8443" ipv4hint="192.0.2.100" ipv6hint="2001:db8::100"`,
+		}},
+		{grpcRouteService, false, []string{
+			`.	0	IN	HTTPS	1 . alpn="h2" ipv4hint="192.0.2.100" ipv6hint="2001:db8::100"`,
+			`.	0	IN	HTTPS	1 . alpn="h2" port="8443" ipv4hint="192.0.2.100" ipv6hint="2001:db8::100"`,
+		}},
+		{tlsRouteService, true, []string{
+			`.	0	IN	HTTPS	1 . port="9443" ipv4hint="192.0.2.100" ipv6hint="2001:db8::100"`,
+		}},
+	}
+
+	for i, tc := range tests {
+		gateway.Spec.Listeners[1].TLS = nil
+		if tc.http3 {
+			gateway.Spec.Listeners[1].TLS = &gatewayapi_v1.ListenerTLSConfig{
+				Options: map[gatewayapi_v1.AnnotationKey]gatewayapi_v1.AnnotationValue{http3OptionKey: "true"},
+			}
+		}
+		var got []string
+		listeners := matchingListeners(gatewayParent(gateway), gatewayapi_v1.ParentReference{Name: "gw-1"}, "ns1", tc.service)
+		for _, rr := range httpsRecords(listeners, tc.service, addrs) {
+			got = append(got, rr.String())
+		}
+		if !slices.Equal(got, tc.expected) {
+			t.Errorf("Test %d: expected %v, got %v", i, tc.expected, got)
+		}
+	}
+}
+
+func TestGatewayAPIPluginHTTPS(t *testing.T) {
+	gatewayIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{gatewayUniqueIndex: gatewayIndexFunc})
+	routeIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{httpRouteHostnameIndex: httpRouteHostnameIndexFunc})
+
+	ipAddressType := gatewayapi_v1.IPAddressType
+	gateway := &gatewayapi_v1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "gw-1", Namespace: "ns1"},
+		Spec: gatewayapi_v1.GatewaySpec{
+			Listeners: []gatewayapi_v1.Listener{{Name: "https", Protocol: gatewayapi_v1.HTTPSProtocolType, Port: 443}},
+		},
+		Status: gatewayapi_v1.GatewayStatus{
+			Addresses: []gatewayapi_v1.GatewayStatusAddress{{Type: &ipAddressType, Value: "192.0.2.100"}},
+		},
+	}
+	route := &gatewayapi_v1.HTTPRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "route-1", Namespace: "ns1"},
+		Spec: gatewayapi_v1.HTTPRouteSpec{
+			CommonRouteSpec: gatewayapi_v1.CommonRouteSpec{ParentRefs: []gatewayapi_v1.ParentReference{{Name: "gw-1"}}},
+			Hostnames:       []gatewayapi_v1.Hostname{"app.example.com"},
+		},
+	}
+	if err := gatewayIndexer.Add(gateway); err != nil {
+		t.Fatal(err)
+	}
+	if err := routeIndexer.Add(route); err != nil {
+		t.Fatal(err)
+	}
+
+	gw := newGateway()
+	gw.Zones = []string{"example.com."}
+	gw.Next = test.NextHandler(dns.RcodeSuccess, nil)
+	gw.ExternalAddrFunc = gw.SelfAddress
+	gw.Controller = &KubeController{hasSynced: true}
+	gw.updateResources([]string{"HTTPRoute"})
+	gw.lookupResource("HTTPRoute").lookup = lookupHttpRouteIndex(
+		informers{&fakeSharedIndexInformer{indexer: routeIndexer}},
+		parentInformers{gateways: informers{&fakeSharedIndexInformer{indexer: gatewayIndexer}}},
+		nil,
+		false,
+	)
+
+	r := test.Case{Qname: "app.example.com.", Qtype: dns.TypeHTTPS}.Msg()
+	w := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := gw.ServeDNS(context.TODO(), w, r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `app.example.com.	60	IN	HTTPS	1 . alpn="h2,http/1.1" ipv4hint="192.0.2.100"`
+	if len(w.Msg.Answer) != 1 || w.Msg.Answer[0].String() != expected {
+		t.Errorf("expected answer %s, got %v", expected, w.Msg.Answer)
+	}
+}
+
+func TestMatchingListeners(t *testing.T) {
+	all := gatewayapi_v1.NamespacesFromAll
+	grpcKind := gatewayapi_v1.RouteGroupKind{Kind: "GRPCRoute"}
+	gateway := &gatewayapi_v1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "gw-1", Namespace: "infra"},
+		Spec: gatewayapi_v1.GatewaySpec{
+			Listeners: []gatewayapi_v1.Listener{
+				{Name: "http", Protocol: gatewayapi_v1.HTTPProtocolType, Port: 80},
+				{
+					Name: "https", Protocol: gatewayapi_v1.HTTPSProtocolType, Port: 443,
+					AllowedRoutes: &gatewayapi_v1.AllowedRoutes{Namespaces: &gatewayapi_v1.RouteNamespaces{From: &all}},
+				},
+				{
+					Name: "grpc", Protocol: gatewayapi_v1.HTTPSProtocolType, Port: 8443,
+					AllowedRoutes: &gatewayapi_v1.AllowedRoutes{Kinds: []gatewayapi_v1.RouteGroupKind{grpcKind}},
+				},
+				{Name: "tls", Protocol: gatewayapi_v1.TLSProtocolType, Port: 443},
+			},
+		},
+	}
+	sectionName := func(name gatewayapi_v1.SectionName) *gatewayapi_v1.SectionName { return &name }
+	port := func(port gatewayapi_v1.PortNumber) *gatewayapi_v1.PortNumber { return &port }
+
+	tests := []struct {
+		ref            gatewayapi_v1.ParentReference
+		routeNamespace string
+		service        routeService
+		expected       []gatewayapi_v1.SectionName
+	}{
+		// all listeners accepting the route
+		{gatewayapi_v1.ParentReference{Name: "gw-1"}, "infra", httpRouteService, []gatewayapi_v1.SectionName{"http", "https"}},
+		// only listeners accepting routes from other namespaces
+		{gatewayapi_v1.ParentReference{Name: "gw-1"}, "apps", httpRouteService, []gatewayapi_v1.SectionName{"https"}},
+		{gatewayapi_v1.ParentReference{Name: "gw-1", SectionName: sectionName("http")}, "infra", httpRouteService, []gatewayapi_v1.SectionName{"http"}},
+		// listener doesn't accept routes from other namespaces
+		{gatewayapi_v1.ParentReference{Name: "gw-1", SectionName: sectionName("http")}, "apps", httpRouteService, nil},
+		{gatewayapi_v1.ParentReference{Name: "gw-1", Port: port(443)}, "infra", httpRouteService, []gatewayapi_v1.SectionName{"https"}},
+		{gatewayapi_v1.ParentReference{Name: "gw-1", Port: port(443)}, "infra", tlsRouteService, []gatewayapi_v1.SectionName{"tls"}},
+		// listener restricted to other kinds of routes
+		{gatewayapi_v1.ParentReference{Name: "gw-1", SectionName: sectionName("grpc")}, "infra", httpRouteService, nil},
+		{gatewayapi_v1.ParentReference{Name: "gw-1", SectionName: sectionName("grpc")}, "infra", grpcRouteService, []gatewayapi_v1.SectionName{"grpc"}},
+		{gatewayapi_v1.ParentReference{Name: "gw-1", SectionName: sectionName("missing")}, "infra", httpRouteService, nil},
+	}
+
+	for i, tc := range tests {
+		var got []gatewayapi_v1.SectionName
+		for _, listener := range matchingListeners(gatewayParent(gateway), tc.ref, tc.routeNamespace, tc.service) {
+			got = append(got, listener.Name)
+		}
+		if !slices.Equal(got, tc.expected) {
+			t.Errorf("Test %d: expected listeners %v, got %v", i, tc.expected, got)
+		}
+	}
+
+	serviceKind := gatewayapi_v1.Kind("Service")
+	coreGroup := gatewayapi_v1.Group("")
+	if isGatewayRef(gatewayapi_v1.ParentReference{Name: "svc", Kind: &serviceKind, Group: &coreGroup}) {
+		t.Errorf("expected a Service parent reference not to refer to a Gateway")
+	}
+	if !isGatewayRef(gatewayapi_v1.ParentReference{Name: "gw-1"}) {
+		t.Errorf("expected a parent reference without kind to refer to a Gateway")
+	}
+}
+
+func TestEffectiveHostnames(t *testing.T) {
+	hostname := func(name gatewayapi_v1.Hostname) *gatewayapi_v1.Hostname { return &name }
+
+	tests := []struct {
+		listener *gatewayapi_v1.Hostname
+		route    []gatewayapi_v1.Hostname
+		expected []string
+	}{
+		// neither has hostnames, there is no name to publish
+		{nil, nil, nil},
+		{nil, []gatewayapi_v1.Hostname{"app.example.com"}, []string{"app.example.com"}},
+		// routes without hostnames inherit the listener hostname
+		{hostname("app.example.com"), nil, []string{"app.example.com"}},
+		{hostname("*.example.com"), nil, []string{"*.example.com"}},
+		{hostname("app.example.com"), []gatewayapi_v1.Hostname{"APP.example.com"}, []string{"APP.example.com"}},
+		{hostname("app.example.com"), []gatewayapi_v1.Hostname{"other.example.com"}, nil},
+		// the more specific hostname wins
+		{hostname("*.example.com"), []gatewayapi_v1.Hostname{"app.example.com", "a.b.example.com", "example.com"}, []string{"app.example.com", "a.b.example.com"}},
+		{hostname("app.example.com"), []gatewayapi_v1.Hostname{"*.example.com", "*.other.com"}, []string{"app.example.com"}},
+		{hostname("*.example.com"), []gatewayapi_v1.Hostname{"*.apps.example.com"}, []string{"*.apps.example.com"}},
+		{hostname("*.apps.example.com"), []gatewayapi_v1.Hostname{"*.example.com"}, []string{"*.apps.example.com"}},
+	}
+
+	for i, tc := range tests {
+		got := effectiveHostnames(gatewayapi_v1.Listener{Hostname: tc.listener}, tc.route)
+		if !slices.Equal(got, tc.expected) {
+			t.Errorf("Test %d: expected hostnames %v, got %v", i, tc.expected, got)
+		}
+	}
+}
+
+func TestLookupRouteHostnameInheritance(t *testing.T) {
+	gatewayIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		gatewayUniqueIndex:           gatewayIndexFunc,
+		gatewayListenerHostnameIndex: gatewayListenerHostnameIndexFunc,
+	})
+	routeIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		httpRouteHostnameIndex: httpRouteHostnameIndexFunc,
+		routeParentIndex:       routeParentIndexFunc,
+	})
+
+	ipAddressType := gatewayapi_v1.IPAddressType
+	newGateway := func(name, addr string, hostname gatewayapi_v1.Hostname) *gatewayapi_v1.Gateway {
+		return &gatewayapi_v1.Gateway{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns1"},
+			Spec: gatewayapi_v1.GatewaySpec{
+				Listeners: []gatewayapi_v1.Listener{{Name: "http", Protocol: gatewayapi_v1.HTTPProtocolType, Port: 80, Hostname: &hostname}},
+			},
+			Status: gatewayapi_v1.GatewayStatus{
+				Addresses: []gatewayapi_v1.GatewayStatusAddress{{Type: &ipAddressType, Value: addr}},
+			},
+		}
+	}
+	newRoute := func(name, gateway string, hostnames ...gatewayapi_v1.Hostname) *gatewayapi_v1.HTTPRoute {
+		return &gatewayapi_v1.HTTPRoute{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns1"},
+			Spec: gatewayapi_v1.HTTPRouteSpec{
+				CommonRouteSpec: gatewayapi_v1.CommonRouteSpec{ParentRefs: []gatewayapi_v1.ParentReference{{Name: gatewayapi_v1.ObjectName(gateway)}}},
+				Hostnames:       hostnames,
+			},
+		}
+	}
+	for _, gw := range []*gatewayapi_v1.Gateway{
+		newGateway("exact", "192.0.2.1", "inherited.example.com"),
+		newGateway("wildcard", "192.0.2.2", "*.apps.example.com"),
+	} {
+		if err := gatewayIndexer.Add(gw); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, route := range []*gatewayapi_v1.HTTPRoute{
+		newRoute("inherited", "exact"),
+		newRoute("narrowed", "exact", "*.example.com"),
+		newRoute("wildcard", "wildcard"),
+		newRoute("specific", "wildcard", "app.apps.example.com", "app.other.com"),
+	} {
+		if err := routeIndexer.Add(route); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lookup := lookupHttpRouteIndex(
+		informers{&fakeSharedIndexInformer{indexer: routeIndexer}},
+		parentInformers{gateways: informers{&fakeSharedIndexInformer{indexer: gatewayIndexer}}},
+		nil,
+		false,
+	)
+
+	tests := []struct {
+		indexKeys []string
+		expected  []string
+	}{
+		// served by both the inheriting and the narrowed route
+		{[]string{"inherited.example.com", "inherited"}, []string{"192.0.2.1", "192.0.2.1"}},
+		// the listener doesn't serve other names of the wildcard route
+		{[]string{"*.example.com", "*"}, nil},
+		{[]string{"*.apps.example.com", "*.apps"}, []string{"192.0.2.2"}},
+		{[]string{"app.apps.example.com", "app.apps"}, []string{"192.0.2.2"}},
+		// outside of the listener hostname
+		{[]string{"app.other.com"}, nil},
+	}
+
+	for i, tc := range tests {
+		var got []string
+		for _, addr := range lookup(tc.indexKeys).addrs {
+			got = append(got, addr.String())
+		}
+		if !slices.Equal(got, tc.expected) {
+			t.Errorf("Test %d: expected addresses %v, got %v", i, tc.expected, got)
+		}
+	}
+}
+
+func TestLookupGatewayIndex(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{gatewayListenerHostnameIndex: gatewayListenerHostnameIndexFunc})
+
+	ipAddressType := gatewayapi_v1.IPAddressType
+	hostname := func(name gatewayapi_v1.Hostname) *gatewayapi_v1.Hostname { return &name }
+	newGateway := func(name, class, addr string, hostnames ...*gatewayapi_v1.Hostname) *gatewayapi_v1.Gateway {
+		gw := &gatewayapi_v1.Gateway{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns1"},
+			Spec:       gatewayapi_v1.GatewaySpec{GatewayClassName: gatewayapi_v1.ObjectName(class)},
+			Status: gatewayapi_v1.GatewayStatus{
+				Addresses: []gatewayapi_v1.GatewayStatusAddress{{Type: &ipAddressType, Value: addr}},
+			},
+		}
+		for _, hostname := range hostnames {
+			gw.Spec.Listeners = append(gw.Spec.Listeners, gatewayapi_v1.Listener{Name: "http", Protocol: gatewayapi_v1.HTTPProtocolType, Port: 80, Hostname: hostname})
+		}
+		return gw
+	}
+	ignored := newGateway("ignored", "public", "192.0.2.3", hostname("ignored.example.com"))
+	ignored.Labels = map[string]string{ignoreLabelKey: "true"}
+	for _, gw := range []*gatewayapi_v1.Gateway{
+		newGateway("apps", "public", "192.0.2.1", hostname("*.Apps.example.com"), nil),
+		newGateway("internal", "private", "192.0.2.2", hostname("internal.example.com")),
+		ignored,
+	} {
+		if err := indexer.Add(gw); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lookup := lookupGatewayIndex(parentInformers{gateways: informers{&fakeSharedIndexInformer{indexer: indexer}}}, []string{"public"})
+
+	tests := []struct {
+		indexKeys []string
+		expected  []string
+	}{
+		{[]string{"*.apps.example.com", "*.apps"}, []string{"192.0.2.1"}},
+		{[]string{"app.apps.example.com", "app.apps"}, nil},
+		// other gatewayClass
+		{[]string{"internal.example.com", "internal"}, nil},
+		{[]string{"ignored.example.com", "ignored"}, nil},
+	}
+
+	for i, tc := range tests {
+		var got []string
+		for _, addr := range lookup(tc.indexKeys).addrs {
+			got = append(got, addr.String())
+		}
+		if !slices.Equal(got, tc.expected) {
+			t.Errorf("Test %d: expected addresses %v, got %v", i, tc.expected, got)
+		}
+	}
+}
@@ -33,8 +33,8 @@ func TestLookup(t *testing.T) {
 	gw.Next = test.NextHandler(dns.RcodeSuccess, nil)
 	gw.ExternalAddrFunc = gw.SelfAddress
 	gw.Controller = ctrl
-	real := []string{"Ingress", "Service", "HTTPRoute", "TLSRoute", "GRPCRoute", "DNSEndpoint"}
-	fake := []string{"Pod", "Gateway"}
+	real := []string{"Ingress", "IngressRoute", "IngressRouteTCP", "HTTPProxy", "Route", "Service", "ServiceImport", "Pod", "HTTPRoute", "TLSRoute", "GRPCRoute", "TCPRoute", "UDPRoute", "Gateway", "VirtualService", "IstioGateway", "DNSEndpoint"}
+	fake := []string{"ConfigMap"}
 
 	for _, resource := range real {
 		if found := gw.lookupResource(resource); found == nil {
@@ -167,56 +167,77 @@ var tests = []test.Case{
 			test.SOA("example.com.  60  IN  SOA dns1.kube-system.example.com. hostmaster.example.com. 1499347823 7200 1800 86400 5"),
 		},
 	},
-	// Ingress FQDN == zone | Test 8
+	// Service with addresses, CNAME query | Test 8
+	{
+		Qname: "svc1.ns1.example.com.", Qtype: dns.TypeCNAME, Rcode: dns.RcodeSuccess,
+		Ns: []dns.RR{
+			test.SOA("example.com.  60  IN  SOA dns1.kube-system.example.com. hostmaster.example.com. 1499347823 7200 1800 86400 5"),
+		},
+	},
+	// Service with addresses, record type it doesn't publish | Test 9
+	{
+		Qname: "svc1.ns1.example.com.", Qtype: dns.TypeMX, Rcode: dns.RcodeSuccess,
+		Ns: []dns.RR{
+			test.SOA("example.com.  60  IN  SOA dns1.kube-system.example.com. hostmaster.example.com. 1499347823 7200 1800 86400 5"),
+		},
+	},
+	// Service with no public addresses, record type it doesn't publish | Test 10
+	{
+		Qname: "svc3.ns1.example.com.", Qtype: dns.TypeMX, Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{
+			test.SOA("example.com.  60  IN  SOA dns1.kube-system.example.com. hostmaster.example.com. 1499347823 7200 1800 86400 5"),
+		},
+	},
+	// Ingress FQDN == zone | Test 11
 	{
 		Qname: "example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
 		Answer: []dns.RR{
 			test.A("example.com.    60  IN  A   192.0.0.3"),
 		},
 	},
-	// Existing Ingress with a mix of lower and upper case letters | Test 9
+	// Existing Ingress with a mix of lower and upper case letters | Test 12
 	{
 		Qname: "dOmAiN.eXamPLe.cOm.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
 		Answer: []dns.RR{
 			test.A("domain.example.com. 60  IN  A   192.0.0.1"),
 		},
 	},
-	// Existing Service with a mix of lower and upper case letters | Test 10
+	// Existing Service with a mix of lower and upper case letters | Test 13
 	{
 		Qname: "svC1.Ns1.exAmplE.Com.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
 		Answer: []dns.RR{
 			test.A("svc1.ns1.example.com.   60  IN  A   192.0.1.1"),
 		},
 	},
-	// Existing Service A record, but no AAAA record | Test 11
+	// Existing Service A record, but no AAAA record | Test 14
 	{
 		Qname: "svc2.ns1.example.com.", Qtype: dns.TypeAAAA, Rcode: dns.RcodeSuccess,
 		Ns: []dns.RR{
 			test.SOA("example.com.  60  IN  SOA dns1.kube-system.example.com. hostmaster.example.com. 1499347823 7200 1800 86400 5"),
 		},
 	},
-	// Existing Service AAAA record only | Test 12
+	// Existing Service AAAA record only | Test 15
 	{
 		Qname: "svc4.ns1.example.com.", Qtype: dns.TypeAAAA, Rcode: dns.RcodeSuccess,
 		Answer: []dns.RR{
 			test.AAAA("svc4.ns1.example.com.    60  IN  AAAA    fd12:3456:789a:2::"),
 		},
 	},
-	// Existing Service AAAA-only record, A query returns NODATA (RFC 4074) | Test 13
+	// Existing Service AAAA-only record, A query returns NODATA (RFC 4074) | Test 16
 	{
 		Qname: "svc4.ns1.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
 		Ns: []dns.RR{
 			test.SOA("example.com.  60  IN  SOA dns1.kube-system.example.com. hostmaster.example.com. 1499347823 7200 1800 86400 5"),
 		},
 	},
-	// Existing Service IPv6 | Test 16
+	// Existing Service IPv6 | Test 19
 	{
 		Qname: "svc1.ns1.example.com.", Qtype: dns.TypeAAAA, Rcode: dns.RcodeSuccess,
 		Answer: []dns.RR{
 			test.AAAA("svc1.ns1.example.com.    60  IN  AAAA    fd12:3456:789a:1::"),
 		},
 	},
-	// lookup apex NS record | Test 17
+	// lookup apex NS record | Test 20
 	{
 		Qname: "example.com.", Qtype: dns.TypeNS, Rcode: dns.RcodeSuccess,
 		Answer: []dns.RR{
@@ -226,14 +247,14 @@ var tests = []test.Case{
 			test.A("dns1.kube-system.example.com.   60  IN  A   192.0.1.53"),
 		},
 	},
-	// Lookup that relies on a wildcard | Test 18
+	// Lookup that relies on a wildcard | Test 21
 	{
 		Qname: "not-explicitly-defined-label.wildcard.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
 		Answer: []dns.RR{
 			test.A("not-explicitly-defined-label.wildcard.example.com. 60  IN  A   192.0.0.6"),
 		},
 	},
-	// Lookup with a matching wildcard but a more specific entry | Test 19
+	// Lookup with a matching wildcard but a more specific entry | Test 22
 	{
 		Qname: "specific-subdomain.wildcard.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
 		Answer: []dns.RR{
@@ -292,11 +313,17 @@ var testServiceIndexes = map[string][]netip.Addr{
 	"dns1.kube-system": {netip.MustParseAddr("192.0.1.53")},
 }
 
-func testServiceLookup(keys []string) (results []netip.Addr, raws []string) {
+var testServicePorts = map[string][]servicePort{
+	"svc1.ns1": {{name: "http", protocol: "TCP", port: 80}, {name: "dns", protocol: "UDP", port: 53}},
+	"svc2.ns1": {{name: "https", protocol: "TCP", port: 443}},
+}
+
+func testServiceLookup(keys []string) (result lookupResult) {
 	for _, key := range keys {
-		results = append(results, testServiceIndexes[strings.ToLower(key)]...)
+		result.addrs = append(result.addrs, testServiceIndexes[strings.ToLower(key)]...)
+		result.ports = append(result.ports, testServicePorts[strings.ToLower(key)]...)
 	}
-	return results, raws
+	return result
 }
 
 var testIngressIndexes = map[string][]netip.Addr{
@@ -309,11 +336,11 @@ var testIngressIndexes = map[string][]netip.Addr{
 	"specific-subdomain.wildcard.example.com": {netip.MustParseAddr("192.0.0.7")},
 }
 
-func testIngressLookup(keys []string) (results []netip.Addr, raws []string) {
+func testIngressLookup(keys []string) (result lookupResult) {
 	for _, key := range keys {
-		results = append(results, testIngressIndexes[strings.ToLower(key)]...)
+		result.addrs = append(result.addrs, testIngressIndexes[strings.ToLower(key)]...)
 	}
-	return results, raws
+	return result
 }
 
 var testDNSEndpointIndexes = map[string][]netip.Addr{
@@ -329,14 +356,14 @@ var testDNSEndpointTxtIndexes = map[string][]string{
 	"endpoint.example.com": {"Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor incididunt ut labore et dolore magna aliqua. Ut enim ad minim veniam, quis nostrud exercitation ullamco laboris nisi ut aliquip ex ea commodo consequat. Duis aute irure dolor in reprehenderit in voluptate velit esse cillum dolore eu fugiat nulla pariatur. Excepteur sint occaecat cupidatat non proident, sunt in culpa qui officia deserunt mollit anim id est laborum."},
 }
 
-func testDNSEndpointLookup(keys []string) (results []netip.Addr, raws []string) {
+func testDNSEndpointLookup(keys []string) (result lookupResult) {
 	for _, key := range keys {
-		results = append(results, testDNSEndpointIndexes[strings.ToLower(key)]...)
+		result.addrs = append(result.addrs, testDNSEndpointIndexes[strings.ToLower(key)]...)
 	}
 	for _, key := range keys {
-		raws = append(raws, testDNSEndpointTxtIndexes[strings.ToLower(key)]...)
+		result.raws = append(result.raws, testDNSEndpointTxtIndexes[strings.ToLower(key)]...)
 	}
-	return results, raws
+	return result
 }
 
 func setupLookupFuncs(gw *Gateway) {
@@ -358,7 +385,7 @@ func TestUpdateResourcesIsolation(t *testing.T) {
 	gw := newGateway()
 	gw.updateResources([]string{"Ingress", "Service", "Node"})
 
-	sentinel := func([]string) ([]netip.Addr, []string) { return nil, nil }
+	sentinel := func([]string) lookupResult { return lookupResult{} }
 
 	for _, r := range gw.Resources {
 		r.lookup = sentinel
@@ -367,8 +394,7 @@ func TestUpdateResourcesIsolation(t *testing.T) {
 	for _, sr := range staticResources {
 		// The lookup field of each staticResources entry must still be noop,
 		// not the sentinel we assigned to gw.Resources entries.
-		addrs, _ := sr.lookup(nil)
-		if len(addrs) != 0 {
+		if !sr.lookup(nil).empty() {
 			t.Errorf("staticResources entry %q was mutated by updateResources", sr.name)
 		}
 		// A direct pointer comparison: if sr is the same struct as the one
@@ -395,3 +421,314 @@ func TestUpdateResourcesUnknown(t *testing.T) {
 		t.Errorf("expected Ingress, got %s", gw.Resources[0].name)
 	}
 }
+
+var testHostnameIndexes = map[string]lookupResult{
+	"lb.example.com":        {hostnames: []string{"abc123.elb.amazonaws.com"}},
+	"alias.example.com":     {hostnames: []string{"domain.example.com"}},
+	"chained.example.com":   {hostnames: []string{"alias.example.com"}},
+	"domain.example.com":    {addrs: []netip.Addr{netip.MustParseAddr("192.0.0.1"), netip.MustParseAddr("fd12:3456:789a:3::")}},
+	"short.example.com":     {addrs: []netip.Addr{netip.MustParseAddr("192.0.0.2")}, ttl: 5},
+	"bluegreen.example.com": {hostnames: []string{"short.example.com"}, ttl: 30},
+	"lb-srv.example.com":    {hostnames: []string{"abc123.elb.amazonaws.com"}, ports: []servicePort{{"http", "TCP", 80}}},
+	"alias-srv.example.com": {hostnames: []string{"chained.example.com"}, ports: []servicePort{{"http", "TCP", 80}}},
+}
+
+func testHostnameLookup(keys []string) (result lookupResult) {
+	for _, key := range keys {
+		result = result.merge(testHostnameIndexes[strings.ToLower(key)])
+	}
+	return result
+}
+
+var testsHostnameAddresses = []test.Case{
+	// Hostname outside of our zones is not chased | Test 0
+	{
+		Qname: "lb.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.CNAME("lb.example.com.  60  IN  CNAME  abc123.elb.amazonaws.com."),
+		},
+	},
+	// CNAME query | Test 1
+	{
+		Qname: "lb.example.com.", Qtype: dns.TypeCNAME, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.CNAME("lb.example.com.  60  IN  CNAME  abc123.elb.amazonaws.com."),
+		},
+	},
+	// In-zone target is chased for A | Test 2
+	{
+		Qname: "alias.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.CNAME("alias.example.com.  60  IN  CNAME  domain.example.com."),
+			test.A("domain.example.com.  60  IN  A  192.0.0.1"),
+		},
+	},
+	// In-zone target is chased for AAAA | Test 3
+	{
+		Qname: "alias.example.com.", Qtype: dns.TypeAAAA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.CNAME("alias.example.com.  60  IN  CNAME  domain.example.com."),
+			test.AAAA("domain.example.com.  60  IN  AAAA  fd12:3456:789a:3::"),
+		},
+	},
+	// CNAME query is not chased | Test 4
+	{
+		Qname: "alias.example.com.", Qtype: dns.TypeCNAME, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.CNAME("alias.example.com.  60  IN  CNAME  domain.example.com."),
+		},
+	},
+	// Chain of in-zone CNAMEs | Test 5
+	{
+		Qname: "chained.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.CNAME("alias.example.com.  60  IN  CNAME  domain.example.com."),
+			test.CNAME("chained.example.com.  60  IN  CNAME  alias.example.com."),
+			test.A("domain.example.com.  60  IN  A  192.0.0.1"),
+		},
+	},
+	// TTL set on the objects | Test 6
+	{
+		Qname: "short.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.A("short.example.com.  5  IN  A  192.0.0.2"),
+		},
+	},
+	// Every record of a chain keeps its own TTL | Test 7
+	{
+		Qname: "bluegreen.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.CNAME("bluegreen.example.com.  30  IN  CNAME  short.example.com."),
+			test.A("short.example.com.  5  IN  A  192.0.0.2"),
+		},
+	},
+	// CNAME query for a name with addresses only | Test 8
+	{
+		Qname: "domain.example.com.", Qtype: dns.TypeCNAME, Rcode: dns.RcodeSuccess,
+		Ns: []dns.RR{
+			test.SOA("example.com.  60  IN  SOA dns1.kube-system.example.com. hostmaster.example.com. 1499347823 7200 1800 86400 5"),
+		},
+	},
+	// SRV target is the load balancer hostname rather than the alias | Test 9
+	{
+		Qname: "_http._tcp.lb-srv.example.com.", Qtype: dns.TypeSRV, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.SRV("_http._tcp.lb-srv.example.com.  60  IN  SRV  0 100 80 abc123.elb.amazonaws.com."),
+		},
+	},
+	// In-zone aliases are followed to the SRV target holding the addresses | Test 10
+	{
+		Qname: "_http._tcp.alias-srv.example.com.", Qtype: dns.TypeSRV, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.SRV("_http._tcp.alias-srv.example.com.  60  IN  SRV  0 100 80 domain.example.com."),
+		},
+		Extra: []dns.RR{
+			test.A("domain.example.com.  60  IN  A  192.0.0.1"),
+			test.AAAA("domain.example.com.  60  IN  AAAA  fd12:3456:789a:3::"),
+		},
+	},
+}
+
+func TestPluginHostnameAddresses(t *testing.T) {
+	ctrl := &KubeController{hasSynced: true}
+
+	gw := newGateway()
+	gw.Zones = []string{"example.com."}
+	gw.Next = test.NextHandler(dns.RcodeSuccess, nil)
+	gw.ExternalAddrFunc = gw.SelfAddress
+	gw.Controller = ctrl
+	gw.cnameHostnames = true
+	gw.lookupResource("Ingress").lookup = testHostnameLookup
+
+	ctx := context.TODO()
+	for i, tc := range testsHostnameAddresses {
+		r := tc.Msg()
+		w := dnstest.NewRecorder(&test.ResponseWriter{})
+
+		_, err := gw.ServeDNS(ctx, w, r)
+		if err != tc.Error {
+			t.Errorf("Test %d expected no error, got %v", i, err)
+			return
+		}
+
+		resp := w.Msg
+		if resp == nil {
+			t.Fatalf("Test %d, got nil message and no error for %q", i, r.Question[0].Name)
+		}
+		if err = test.SortAndCheck(resp, tc); err != nil {
+			t.Errorf("Test %d failed with error: %v", i, err)
+		}
+	}
+}
+
+// TestResolveHostnamesMixedResult verifies that hostnames are only kept for a
+// CNAME answer when the result holds no addresses that would conflict with it.
+func TestResolveHostnamesMixedResult(t *testing.T) {
+	gw := newGateway()
+	gw.cnameHostnames = true
+
+	result := gw.resolveHostnames(lookupResult{hostnames: []string{"lb.invalid"}})
+	if len(result.hostnames) != 1 {
+		t.Errorf("expected hostname to be kept in cname mode, got %v", result)
+	}
+
+	result = gw.resolveHostnames(lookupResult{
+		addrs:     []netip.Addr{netip.MustParseAddr("192.0.2.1")},
+		hostnames: []string{"lb.invalid"},
+	})
+	if len(result.hostnames) != 0 || len(result.addrs) != 1 {
+		t.Errorf("expected hostnames to be resolved next to addresses, got %v", result)
+	}
+}
+
+func TestPluginSRV(t *testing.T) {
+	ctrl := &KubeController{hasSynced: true}
+
+	gw := newGateway()
+	gw.Zones = []string{"example.com."}
+	gw.Next = test.NextHandler(dns.RcodeSuccess, nil)
+	gw.ExternalAddrFunc = gw.SelfAddress
+	gw.Controller = ctrl
+	setupLookupFuncs(gw)
+
+	ctx := context.TODO()
+	for i, tc := range testsSRV {
+		r := tc.Msg()
+		w := dnstest.NewRecorder(&test.ResponseWriter{})
+
+		_, err := gw.ServeDNS(ctx, w, r)
+		if err != tc.Error {
+			t.Errorf("Test %d expected no error, got %v", i, err)
+			return
+		}
+
+		resp := w.Msg
+		if resp == nil {
+			t.Fatalf("Test %d, got nil message and no error for %q", i, r.Question[0].Name)
+		}
+		if err = test.SortAndCheck(resp, tc); err != nil {
+			t.Errorf("Test %d failed with error: %v", i, err)
+		}
+	}
+}
+
+var testsSRV = []test.Case{
+	// SRV record with target addresses in the additional section | Test 0
+	{
+		Qname: "_http._tcp.svc1.ns1.example.com.", Qtype: dns.TypeSRV, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.SRV("_http._tcp.svc1.ns1.example.com.  60  IN  SRV  0 100 80 svc1.ns1.example.com."),
+		},
+		Extra: []dns.RR{
+			test.A("svc1.ns1.example.com.  60  IN  A  192.0.1.1"),
+			test.AAAA("svc1.ns1.example.com.  60  IN  AAAA  fd12:3456:789a:1::"),
+		},
+	},
+	// UDP port | Test 1
+	{
+		Qname: "_dns._udp.svc1.ns1.example.com.", Qtype: dns.TypeSRV, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.SRV("_dns._udp.svc1.ns1.example.com.  60  IN  SRV  0 100 53 svc1.ns1.example.com."),
+		},
+		Extra: []dns.RR{
+			test.A("svc1.ns1.example.com.  60  IN  A  192.0.1.1"),
+			test.AAAA("svc1.ns1.example.com.  60  IN  AAAA  fd12:3456:789a:1::"),
+		},
+	},
+	// Port name with the wrong protocol | Test 2
+	{
+		Qname: "_http._udp.svc1.ns1.example.com.", Qtype: dns.TypeSRV, Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{
+			test.SOA("example.com.  60  IN  SOA dns1.kube-system.example.com. hostmaster.example.com. 1499347823 7200 1800 86400 5"),
+		},
+	},
+	// Address query for an SRV name | Test 3
+	{
+		Qname: "_http._tcp.svc1.ns1.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Ns: []dns.RR{
+			test.SOA("example.com.  60  IN  SOA dns1.kube-system.example.com. hostmaster.example.com. 1499347823 7200 1800 86400 5"),
+		},
+	},
+	// Ports are taken from the Service even when an Ingress holds the hostname | Test 4
+	{
+		Qname: "_https._tcp.svc2.ns1.example.com.", Qtype: dns.TypeSRV, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.SRV("_https._tcp.svc2.ns1.example.com.  60  IN  SRV  0 100 443 svc2.ns1.example.com."),
+		},
+		Extra: []dns.RR{
+			test.A("svc2.ns1.example.com.  60  IN  A  192.0.1.2"),
+		},
+	},
+	// Unknown hostname | Test 5
+	{
+		Qname: "_http._tcp.unknown.example.com.", Qtype: dns.TypeSRV, Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{
+			test.SOA("example.com.  60  IN  SOA dns1.kube-system.example.com. hostmaster.example.com. 1499347823 7200 1800 86400 5"),
+		},
+	},
+}
+
+func TestParseSRVQueryName(t *testing.T) {
+	tests := []struct {
+		qname string
+		want  srvQuery
+		ok    bool
+	}{
+		{"_http._tcp.svc1.ns1.example.com.", srvQuery{"http", "tcp", "svc1.ns1.example.com."}, true},
+		{"_http._tcp.example.com.", srvQuery{"http", "tcp", "example.com."}, true},
+		{"_http.svc1.ns1.example.com.", srvQuery{}, false},
+		{"_._tcp.svc1.ns1.example.com.", srvQuery{}, false},
+		{"svc1.ns1.example.com.", srvQuery{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseSRVQueryName(tt.qname, "example.com.")
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseSRVQueryName(%q) = %v, %v; want %v, %v", tt.qname, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestPluginNotSynced(t *testing.T) {
+	gw := newGateway()
+	gw.Zones = []string{"example.com."}
+	gw.Next = test.NextHandler(dns.RcodeSuccess, nil)
+	gw.Controller = &KubeController{}
+	setupLookupFuncs(gw)
+
+	if gw.Ready() {
+		t.Errorf("expected the plugin not to be ready before the resources synced")
+	}
+
+	for _, do := range []bool{true, false} {
+		r := test.Case{Qname: "domain.example.com.", Qtype: dns.TypeA, Do: do}.Msg()
+		if !do {
+			r.Extra = nil
+		}
+		w := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := gw.ServeDNS(context.TODO(), w, r); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if w.Msg.Rcode != dns.RcodeServerFailure {
+			t.Errorf("expected SERVFAIL, got %s", dns.RcodeToString[w.Msg.Rcode])
+		}
+
+		opt := w.Msg.IsEdns0()
+		if !do {
+			if opt != nil {
+				t.Errorf("expected no OPT record for a request without EDNS")
+			}
+			continue
+		}
+		if opt == nil || len(opt.Option) != 1 {
+			t.Fatalf("expected an Extended DNS Error, got %v", opt)
+		}
+		if ede, ok := opt.Option[0].(*dns.EDNS0_EDE); !ok || ede.InfoCode != dns.ExtendedErrorCodeNotReady {
+			t.Errorf("expected a Not Ready Extended DNS Error, got %v", opt.Option[0])
+		}
+	}
+
+	gw.Controller.hasSynced = true
+	if !gw.Ready() {
+		t.Errorf("expected the plugin to be ready once the resources synced")
+	}
+}
@@ -0,0 +1,467 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"strings"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	istioGatewayUniqueIndex     = "istioGatewayIndex"
+	istioGatewayHostnameIndex   = "istioGatewayHostname"
+	virtualServiceHostnameIndex = "virtualServiceHostname"
+	serviceSelectorIndex        = "serviceSelector"
+	// istioSystemNamespace holds the ingress gateway Services of a default Istio install
+	istioSystemNamespace = "istio-system"
+	// istioMeshGateway is the reserved gateway name binding a VirtualService to sidecars
+	istioMeshGateway = "mesh"
+)
+
+var istioCRDClient rest.Interface
+
+var istioGroupVersion = schema.GroupVersion{Group: "networking.istio.io", Version: "v1beta1"}
+
+// istioGateway and virtualService hold the fields of the networking.istio.io
+// resources needed to publish them, so that no Istio dependency is pulled in.
+type istioGateway struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              istioGatewaySpec `json:"spec"`
+}
+
+type istioGatewaySpec struct {
+	Servers  []istioServer     `json:"servers,omitempty"`
+	Selector map[string]string `json:"selector,omitempty"`
+}
+
+type istioServer struct {
+	Hosts []string `json:"hosts,omitempty"`
+}
+
+type istioGatewayList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []istioGateway `json:"items"`
+}
+
+type virtualService struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              virtualServiceSpec `json:"spec"`
+}
+
+type virtualServiceSpec struct {
+	Hosts    []string `json:"hosts,omitempty"`
+	Gateways []string `json:"gateways,omitempty"`
+}
+
+type virtualServiceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []virtualService `json:"items"`
+}
+
+func (in *istioGateway) DeepCopyObject() runtime.Object {
+	out := &istioGateway{TypeMeta: in.TypeMeta}
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec.Selector = maps.Clone(in.Spec.Selector)
+	for _, server := range in.Spec.Servers {
+		out.Spec.Servers = append(out.Spec.Servers, istioServer{Hosts: slices.Clone(server.Hosts)})
+	}
+	return out
+}
+
+func (in *istioGatewayList) DeepCopyObject() runtime.Object {
+	out := &istioGatewayList{TypeMeta: in.TypeMeta}
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	for i := range in.Items {
+		out.Items = append(out.Items, *in.Items[i].DeepCopyObject().(*istioGateway))
+	}
+	return out
+}
+
+func (in *virtualService) DeepCopyObject() runtime.Object {
+	out := &virtualService{TypeMeta: in.TypeMeta}
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec.Hosts = slices.Clone(in.Spec.Hosts)
+	out.Spec.Gateways = slices.Clone(in.Spec.Gateways)
+	return out
+}
+
+func (in *virtualServiceList) DeepCopyObject() runtime.Object {
+	out := &virtualServiceList{TypeMeta: in.TypeMeta}
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	for i := range in.Items {
+		out.Items = append(out.Items, *in.Items[i].DeepCopyObject().(*virtualService))
+	}
+	return out
+}
+
+func addIstioTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypeWithName(istioGroupVersion.WithKind("Gateway"), &istioGateway{})
+	scheme.AddKnownTypeWithName(istioGroupVersion.WithKind("GatewayList"), &istioGatewayList{})
+	scheme.AddKnownTypeWithName(istioGroupVersion.WithKind("VirtualService"), &virtualService{})
+	scheme.AddKnownTypeWithName(istioGroupVersion.WithKind("VirtualServiceList"), &virtualServiceList{})
+	metav1.AddToGroupVersion(scheme, istioGroupVersion)
+	return nil
+}
+
+func newIstioRESTClient(config *rest.Config) (rest.Interface, error) {
+	return newCRDRESTClient(config, istioGroupVersion, addIstioTypes)
+}
+
+// initializeIstioController watches Istio Gateways and VirtualServices, together with
+// the Services Istio Gateways select, whose addresses they are published with.
+func initializeIstioController(ctx context.Context, ctrl *KubeController, gw *Gateway) {
+	configuredResources := dereferenceStrings(gw.ConfiguredResources)
+	if !slices.Contains(configuredResources, "IstioGateway") && !slices.Contains(configuredResources, "VirtualService") {
+		return
+	}
+	if istioCRDClient == nil || !crdServesVersion(apiextensionsClient, "gateways.networking.istio.io", istioGroupVersion.Version) {
+		return
+	}
+
+	istioGatewayControllers := ctrl.forEachNamespace(func(ns string) cache.SharedIndexInformer {
+		return cache.NewSharedIndexInformer(
+			&cache.ListWatch{
+				ListFunc:  istioLister(ctx, "gateways", ns),
+				WatchFunc: istioWatcher(ctx, "gateways", ns),
+			},
+			&istioGateway{},
+			defaultResyncPeriod,
+			cache.Indexers{
+				istioGatewayUniqueIndex:   istioGatewayIndexFunc,
+				istioGatewayHostnameIndex: istioGatewayHostnameIndexFunc,
+			},
+		)
+	})
+	ctrl.addController("IstioGateway", istioGatewayControllers...)
+
+	serviceControllers := istioGatewayServices(ctx, ctrl)
+
+	if slices.Contains(configuredResources, "IstioGateway") {
+		if resource := gw.lookupResource("IstioGateway"); resource != nil {
+			resource.lookup = lookupIstioGatewayIndex(istioGatewayControllers, serviceControllers)
+			resource.keys = listIndexKeys(istioGatewayHostnameIndex, istioGatewayControllers...)
+		}
+	}
+	log.Infof("IstioGateway controller initialized")
+
+	if !slices.Contains(configuredResources, "VirtualService") || !crdServesVersion(apiextensionsClient, "virtualservices.networking.istio.io", istioGroupVersion.Version) {
+		return
+	}
+	resource := gw.lookupResource("VirtualService")
+	if resource == nil {
+		return
+	}
+	virtualServiceControllers := ctrl.forEachNamespace(func(ns string) cache.SharedIndexInformer {
+		return cache.NewSharedIndexInformer(
+			&cache.ListWatch{
+				ListFunc:  istioLister(ctx, "virtualservices", ns),
+				WatchFunc: istioWatcher(ctx, "virtualservices", ns),
+			},
+			&virtualService{},
+			defaultResyncPeriod,
+			cache.Indexers{virtualServiceHostnameIndex: virtualServiceHostnameIndexFunc},
+		)
+	})
+	resource.lookup = lookupVirtualServiceIndex(virtualServiceControllers, istioGatewayControllers, serviceControllers)
+	resource.keys = listIndexKeys(virtualServiceHostnameIndex, virtualServiceControllers...)
+	ctrl.addController("VirtualService", virtualServiceControllers...)
+	log.Infof("VirtualService controller initialized")
+}
+
+// istioGatewayServices returns the Service informers to find the Services selected
+// by Istio Gateways in, indexed by their selector. Gateways usually select the
+// ingress gateway in istio-system, so when that namespace isn't watched its Services
+// are watched in addition.
+func istioGatewayServices(ctx context.Context, ctrl *KubeController) informers {
+	indexers := cache.Indexers{serviceSelectorIndex: serviceSelectorIndexFunc}
+	services := ctrl.sharedServices(ctx, indexers)
+	if filter := ctrl.namespaceFilter; filter != nil {
+		// the shared informers watch istio-system already, only hidden when not selected
+		var result informers
+		for _, informer := range services {
+			if filtered, ok := informer.(*filteredInformer); ok {
+				informer = &filteredInformer{
+					SharedIndexInformer: filtered.SharedIndexInformer,
+					allows:              func(ns string) bool { return ns == istioSystemNamespace || filter.allows(ns) },
+				}
+			}
+			result = append(result, informer)
+		}
+		return result
+	}
+	if slices.Contains(ctrl.namespaces, core.NamespaceAll) || slices.Contains(ctrl.namespaces, istioSystemNamespace) {
+		return services
+	}
+
+	gatewayServiceController := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc:  serviceLister(ctx, ctrl.client, istioSystemNamespace, ""),
+			WatchFunc: serviceWatcher(ctx, ctrl.client, istioSystemNamespace, ""),
+		},
+		&core.Service{},
+		defaultResyncPeriod,
+		indexers,
+	)
+	// limited to istio-system, so counted apart from the shared Service informers
+	ctrl.addController("IstioGatewayService", gatewayServiceController)
+	return append(slices.Clone(services), gatewayServiceController)
+}
+
+func istioWatcher(ctx context.Context, resource, ns string) func(metav1.ListOptions) (watch.Interface, error) {
+	return func(opts metav1.ListOptions) (watch.Interface, error) {
+		opts.Watch = true
+		return istioCRDClient.Get().
+			Resource(resource).
+			Namespace(ns).
+			VersionedParams(&opts, metav1.ParameterCodec).
+			Watch(ctx)
+	}
+}
+
+func istioLister(ctx context.Context, resource, ns string) func(metav1.ListOptions) (runtime.Object, error) {
+	return func(opts metav1.ListOptions) (runtime.Object, error) {
+		return istioCRDClient.Get().
+			Resource(resource).
+			Namespace(ns).
+			VersionedParams(&opts, metav1.ParameterCodec).
+			Do(ctx).
+			Get()
+	}
+}
+
+// serviceSelectorIndexFunc indexes a Service by every key=value pair of its selector
+func serviceSelectorIndexFunc(obj interface{}) ([]string, error) {
+	service, ok := obj.(*core.Service)
+	if !ok {
+		return []string{}, nil
+	}
+	var pairs []string
+	for key, value := range service.Spec.Selector {
+		pairs = append(pairs, key+"="+value)
+	}
+	return pairs, nil
+}
+
+func istioGatewayIndexFunc(obj interface{}) ([]string, error) {
+	gw, ok := obj.(*istioGateway)
+	if !ok {
+		return []string{}, nil
+	}
+	return []string{fmt.Sprintf("%s/%s", gw.Namespace, gw.Name)}, nil
+}
+
+func istioGatewayHostnameIndexFunc(obj interface{}) ([]string, error) {
+	gw, ok := obj.(*istioGateway)
+	if !ok {
+		return []string{}, nil
+	}
+
+	if checkIgnoreLabel(gw.Labels) {
+		log.Debugf("Ignoring istioGateway %s due to %s label", gw.Name, ignoreLabelKey)
+		return []string{}, nil
+	}
+
+	var hostnames []string
+	for _, server := range gw.Spec.Servers {
+		for _, serverHost := range server.Hosts {
+			hostname := strings.ToLower(istioServerHostname(serverHost))
+			if hostname == "*" || slices.Contains(hostnames, hostname) || !checkDomainValid(hostname) {
+				continue
+			}
+			log.Debugf("Adding index %s for istioGateway %s", hostname, gw.Name)
+			hostnames = append(hostnames, hostname)
+		}
+	}
+	return hostnames, nil
+}
+
+func virtualServiceHostnameIndexFunc(obj interface{}) ([]string, error) {
+	vs, ok := obj.(*virtualService)
+	if !ok {
+		return []string{}, nil
+	}
+
+	if checkIgnoreLabel(vs.Labels) {
+		log.Debugf("Ignoring virtualService %s due to %s label", vs.Name, ignoreLabelKey)
+		return []string{}, nil
+	}
+
+	var hostnames []string
+	for _, host := range vs.Spec.Hosts {
+		hostname := strings.ToLower(host)
+		if hostname == "*" || slices.Contains(hostnames, hostname) || !checkDomainValid(hostname) {
+			continue
+		}
+		log.Debugf("Adding index %s for virtualService %s", hostname, vs.Name)
+		hostnames = append(hostnames, hostname)
+	}
+	return hostnames, nil
+}
+
+// istioServerHostname strips the namespace a host of a Gateway server may be
+// prefixed with, e.g. "team-a/app.example.com"
+func istioServerHostname(serverHost string) string {
+	if _, hostname, found := strings.Cut(serverHost, "/"); found {
+		return hostname
+	}
+	return serverHost
+}
+
+// istioServerHostMatches reports whether a host of a Gateway server exposes the
+// host of a VirtualService. The namespace prefix restricts the VirtualServices bound
+// to the server, "*" allows any namespace and "." the Gateway's own.
+func istioServerHostMatches(serverHost, gwNamespace, vsNamespace, host string) bool {
+	ns, hostname, found := strings.Cut(serverHost, "/")
+	if !found {
+		ns, hostname = "*", serverHost
+	}
+	switch ns {
+	case "*":
+	case ".":
+		if vsNamespace != gwNamespace {
+			return false
+		}
+	default:
+		if ns != vsNamespace {
+			return false
+		}
+	}
+	return hostname == "*" || strings.EqualFold(hostname, host) || wildcardMatches(hostname, host)
+}
+
+// istioGatewayAddresses returns the addresses of the Services selecting the
+// workloads of an Istio Gateway, i.e. whose selector includes the Gateway's
+func istioGatewayAddresses(services informers, gw *istioGateway) (result lookupResult) {
+	if len(gw.Spec.Selector) == 0 {
+		log.Debugf("Skipping istioGateway %s/%s without selector", gw.Namespace, gw.Name)
+		return
+	}
+	selector := labels.SelectorFromSet(gw.Spec.Selector)
+
+	// any pair of the Gateway's selector narrows the Services down to check
+	key := slices.Min(slices.Collect(maps.Keys(gw.Spec.Selector)))
+	for _, obj := range services.byIndex(serviceSelectorIndex, key+"="+gw.Spec.Selector[key]) {
+		service, _ := obj.(*core.Service)
+		if !selector.Matches(labels.Set(service.Spec.Selector)) {
+			continue
+		}
+		log.Debugf("Found service %s/%s for istioGateway %s/%s", service.Namespace, service.Name, gw.Namespace, gw.Name)
+		result.observe(service)
+
+		if len(service.Spec.ExternalIPs) > 0 {
+			for _, ip := range service.Spec.ExternalIPs {
+				if addr, err := netip.ParseAddr(ip); err == nil {
+					result.addrs = append(result.addrs, addr)
+				}
+			}
+			continue
+		}
+		addrs, names := fetchServiceLoadBalancerIPs(service.Status.LoadBalancer.Ingress)
+		result.addrs = append(result.addrs, addrs...)
+		result.hostnames = append(result.hostnames, names...)
+	}
+	return
+}
+
+func lookupIstioGatewayIndex(gateways, services informers) func([]string) lookupResult {
+	return func(indexKeys []string) (result lookupResult) {
+		var objs []interface{}
+		for _, key := range indexKeys {
+			objs = append(objs, gateways.byIndex(istioGatewayHostnameIndex, strings.ToLower(key))...)
+		}
+		log.Debugf("Found %d matching istioGateway objects", len(objs))
+
+		for _, obj := range objs {
+			gw, _ := obj.(*istioGateway)
+			result.lowerTTL(annotationTTL(gw.ObjectMeta))
+			result.observe(gw)
+			result = result.merge(istioGatewayAddresses(services, gw))
+		}
+		return
+	}
+}
+
+func lookupVirtualServiceIndex(virtualServices, gateways, services informers) func([]string) lookupResult {
+	return func(indexKeys []string) (result lookupResult) {
+		seen := make(map[string]struct{})
+		var objs []interface{}
+		for _, key := range indexKeys {
+			for _, obj := range virtualServices.byIndex(virtualServiceHostnameIndex, strings.ToLower(key)) {
+				vs, _ := obj.(*virtualService)
+				nsName := vs.Namespace + "/" + vs.Name
+				if _, dup := seen[nsName]; dup {
+					continue
+				}
+				seen[nsName] = struct{}{}
+				objs = append(objs, obj)
+			}
+		}
+		log.Debugf("Found %d matching virtualService objects", len(objs))
+
+		for _, obj := range objs {
+			vs, _ := obj.(*virtualService)
+
+			// a VirtualService may hold other hosts as well
+			var hosts []string
+			for _, host := range vs.Spec.Hosts {
+				if slices.ContainsFunc(indexKeys, func(key string) bool { return strings.EqualFold(key, host) }) {
+					hosts = append(hosts, host)
+				}
+			}
+
+			var vsResult lookupResult
+			for _, gwRef := range vs.Spec.Gateways {
+				if gwRef == istioMeshGateway {
+					continue
+				}
+				key := gwRef
+				if !strings.Contains(gwRef, "/") {
+					key = fmt.Sprintf("%s/%s", vs.Namespace, gwRef)
+				}
+
+				for _, gwObj := range gateways.byIndex(istioGatewayUniqueIndex, key) {
+					gw, _ := gwObj.(*istioGateway)
+					if !istioGatewayExposes(gw, vs.Namespace, hosts) {
+						log.Debugf("Skipping istioGateway %s, no server exposes virtualService %s/%s", key, vs.Namespace, vs.Name)
+						continue
+					}
+					vsResult.lowerTTL(annotationTTL(gw.ObjectMeta))
+					vsResult.observe(gw)
+					vsResult = vsResult.merge(istioGatewayAddresses(services, gw))
+				}
+			}
+			result = result.merge(withRouteTTL(vsResult, vs.ObjectMeta))
+		}
+		return
+	}
+}
+
+// istioGatewayExposes reports whether one of the Gateway's servers exposes one of
+// the hosts of a VirtualService in the given namespace
+func istioGatewayExposes(gw *istioGateway, vsNamespace string, hosts []string) bool {
+	for _, server := range gw.Spec.Servers {
+		for _, serverHost := range server.Hosts {
+			for _, host := range hosts {
+				if istioServerHostMatches(serverHost, gw.Namespace, vsNamespace, host) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
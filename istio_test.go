@@ -0,0 +1,234 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/kubernetes/fake"
+	fakeRest "k8s.io/client-go/rest/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestIstioRESTClientDecoding(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := addIstioTypes(scheme); err != nil {
+		t.Fatal(err)
+	}
+	codecFactory := serializer.WithoutConversionCodecFactory{
+		CodecFactory: serializer.NewCodecFactory(scheme),
+	}
+	body := `{"apiVersion": "networking.istio.io/v1beta1", "kind": "GatewayList", "items": [{
+		"metadata": {"name": "ingress", "namespace": "istio-system"},
+		"spec": {"selector": {"istio": "ingressgateway"}, "servers": [{"port": {"number": 443}, "hosts": ["*/app.example.com"]}]}
+	}]}`
+
+	client := &fakeRest.RESTClient{
+		GroupVersion:         istioGroupVersion,
+		VersionedAPIPath:     "/apis/" + istioGroupVersion.String(),
+		NegotiatedSerializer: codecFactory,
+		Client: fakeRest.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Header: defaultHeader(), Body: io.NopCloser(strings.NewReader(body))}, nil
+		}),
+	}
+	old := istioCRDClient
+	istioCRDClient = client
+	defer func() { istioCRDClient = old }()
+
+	obj, err := istioLister(context.TODO(), "gateways", "istio-system")(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error listing Istio Gateways: %s", err)
+	}
+	list, ok := obj.(*istioGatewayList)
+	if !ok || len(list.Items) != 1 {
+		t.Fatalf("Expected a list of one Istio Gateway, got %#v", obj)
+	}
+	gw := list.Items[0]
+	if gw.Spec.Selector["istio"] != "ingressgateway" || !slices.Equal(gw.Spec.Servers[0].Hosts, []string{"*/app.example.com"}) {
+		t.Errorf("Unexpected Istio Gateway spec %+v", gw.Spec)
+	}
+}
+
+func TestIstioServerHostMatches(t *testing.T) {
+	tests := []struct {
+		serverHost  string
+		vsNamespace string
+		host        string
+		expected    bool
+	}{
+		{"app.example.com", "team-a", "app.example.com", true},
+		{"*/app.example.com", "team-a", "APP.example.com", true},
+		{"*/*.example.com", "team-a", "app.example.com", true},
+		{"*/*", "team-a", "app.example.com", true},
+		{"team-a/app.example.com", "team-a", "app.example.com", true},
+		{"team-a/app.example.com", "team-b", "app.example.com", false},
+		{"./app.example.com", "istio-system", "app.example.com", true},
+		{"./app.example.com", "team-a", "app.example.com", false},
+		{"*/other.example.com", "team-a", "app.example.com", false},
+	}
+
+	for i, tc := range tests {
+		if got := istioServerHostMatches(tc.serverHost, "istio-system", tc.vsNamespace, tc.host); got != tc.expected {
+			t.Errorf("Test %d: expected %t for %s and %s/%s, got %t", i, tc.expected, tc.serverHost, tc.vsNamespace, tc.host, got)
+		}
+	}
+}
+
+func TestLookupVirtualService(t *testing.T) {
+	gatewayIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		istioGatewayUniqueIndex:   istioGatewayIndexFunc,
+		istioGatewayHostnameIndex: istioGatewayHostnameIndexFunc,
+	})
+	serviceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{serviceSelectorIndex: serviceSelectorIndexFunc})
+	virtualServiceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		virtualServiceHostnameIndex: virtualServiceHostnameIndexFunc,
+	})
+
+	for _, gw := range []*istioGateway{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "public", Namespace: "istio-system"},
+			Spec: istioGatewaySpec{
+				Selector: map[string]string{"istio": "ingressgateway"},
+				Servers:  []istioServer{{Hosts: []string{"*/*.example.com"}}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "internal", Namespace: "istio-system"},
+			Spec: istioGatewaySpec{
+				Selector: map[string]string{"istio": "internalgateway"},
+				Servers:  []istioServer{{Hosts: []string{"./internal.example.com"}}},
+			},
+		},
+	} {
+		if err := gatewayIndexer.Add(gw); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, svc := range []*core.Service{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "istio-ingressgateway", Namespace: "istio-system"},
+			Spec: core.ServiceSpec{
+				Type:     core.ServiceTypeLoadBalancer,
+				Selector: map[string]string{"app": "istio-ingressgateway", "istio": "ingressgateway"},
+			},
+			Status: core.ServiceStatus{LoadBalancer: core.LoadBalancerStatus{Ingress: []core.LoadBalancerIngress{{IP: "192.0.2.10"}}}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "istio-internalgateway", Namespace: "istio-system"},
+			Spec: core.ServiceSpec{
+				Type:     core.ServiceTypeLoadBalancer,
+				Selector: map[string]string{"istio": "internalgateway"},
+			},
+			Status: core.ServiceStatus{LoadBalancer: core.LoadBalancerStatus{Ingress: []core.LoadBalancerIngress{{IP: "192.0.2.20"}}}},
+		},
+	} {
+		if err := serviceIndexer.Add(svc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, vs := range []*virtualService{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-a"},
+			Spec:       virtualServiceSpec{Hosts: []string{"app.example.com"}, Gateways: []string{"istio-system/public", "mesh"}},
+		},
+		{
+			// the internal Gateway only binds VirtualServices of its own namespace
+			ObjectMeta: metav1.ObjectMeta{Name: "internal", Namespace: "team-a"},
+			Spec:       virtualServiceSpec{Hosts: []string{"internal.example.com"}, Gateways: []string{"istio-system/internal"}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "mesh-only", Namespace: "team-a"},
+			Spec:       virtualServiceSpec{Hosts: []string{"mesh.example.com"}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "ignored", Namespace: "team-a", Labels: map[string]string{ignoreLabelKey: "true"}},
+			Spec:       virtualServiceSpec{Hosts: []string{"ignored.example.com"}, Gateways: []string{"istio-system/public"}},
+		},
+	} {
+		if err := virtualServiceIndexer.Add(vs); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gateways := informers{&fakeSharedIndexInformer{indexer: gatewayIndexer}}
+	services := informers{&fakeSharedIndexInformer{indexer: serviceIndexer}}
+	vsLookup := lookupVirtualServiceIndex(informers{&fakeSharedIndexInformer{indexer: virtualServiceIndexer}}, gateways, services)
+	gwLookup := lookupIstioGatewayIndex(gateways, services)
+
+	tests := []struct {
+		lookup    lookupFunc
+		indexKeys []string
+		expected  []string
+	}{
+		{vsLookup, []string{"app.example.com"}, []string{"192.0.2.10"}},
+		{vsLookup, []string{"internal.example.com"}, nil},
+		{vsLookup, []string{"mesh.example.com"}, nil},
+		{vsLookup, []string{"ignored.example.com"}, nil},
+		{gwLookup, []string{"*.example.com"}, []string{"192.0.2.10"}},
+		{gwLookup, []string{"internal.example.com"}, []string{"192.0.2.20"}},
+	}
+
+	for i, tc := range tests {
+		var got []string
+		for _, addr := range tc.lookup(tc.indexKeys).addrs {
+			got = append(got, addr.String())
+		}
+		if !slices.Equal(got, tc.expected) {
+			t.Errorf("Test %d: expected addresses %v, got %v", i, tc.expected, got)
+		}
+	}
+}
+
+func TestIstioGatewayServicesNamespaceScoped(t *testing.T) {
+	ctx := context.TODO()
+	gw := &istioGateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "public", Namespace: "team-a"},
+		Spec:       istioGatewaySpec{Selector: map[string]string{"istio": "ingressgateway"}},
+	}
+
+	// istio-system isn't watched, so its Services are watched on their own
+	ctrl := &KubeController{client: fake.NewClientset(), namespaces: []string{"team-a"}}
+	services := istioGatewayServices(ctx, ctrl)
+	if !slices.Equal(ctrl.resources, []string{"Service", "IstioGatewayService"}) {
+		t.Fatalf("expected the istio-system Services to be watched, got %v", ctrl.resources)
+	}
+	if len(ctrl.services) != 1 {
+		t.Errorf("expected the shared Service informers to be left alone, got %d", len(ctrl.services))
+	}
+
+	for _, svc := range []*core.Service{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "istio-ingressgateway", Namespace: "istio-system"},
+			Spec: core.ServiceSpec{
+				Type:     core.ServiceTypeLoadBalancer,
+				Selector: map[string]string{"app": "istio-ingressgateway", "istio": "ingressgateway"},
+			},
+			Status: core.ServiceStatus{LoadBalancer: core.LoadBalancerStatus{Ingress: []core.LoadBalancerIngress{{IP: "192.0.2.10"}}}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "istio-system"},
+			Spec:       core.ServiceSpec{Selector: map[string]string{"istio": "egressgateway"}},
+		},
+	} {
+		if err := services[1].GetIndexer().Add(svc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	addrs := istioGatewayAddresses(services, gw).addrs
+	if len(addrs) != 1 || addrs[0].String() != "192.0.2.10" {
+		t.Errorf("expected the istio-system gateway Service address, got %v", addrs)
+	}
+
+	// a watched istio-system needs no informer of its own
+	ctrl = &KubeController{client: fake.NewClientset(), namespaces: []string{"istio-system", "team-a"}}
+	if services := istioGatewayServices(ctx, ctrl); len(services) != 2 || slices.Contains(ctrl.resources, "IstioGatewayService") {
+		t.Errorf("expected only the shared Service informers, got %v", ctrl.resources)
+	}
+}
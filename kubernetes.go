@@ -3,11 +3,15 @@ package gateway
 import (
 	"context"
 	"fmt"
+	"math"
 	"net"
 	"net/netip"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/miekg/dns"
 	core "k8s.io/api/core/v1"
@@ -17,13 +21,17 @@ import (
 	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	meta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 	gatewayapi_v1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayapi_v1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
 	gatewayClient "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned"
 )
 
@@ -33,6 +41,8 @@ const (
 	serviceHostnameIndex             = "serviceHostname"
 	endpointSliceServiceIndex        = "endpointSliceService"
 	gatewayUniqueIndex               = "gatewayIndex"
+	gatewayListenerHostnameIndex     = "gatewayListenerHostname"
+	routeParentIndex                 = "routeParent"
 	httpRouteHostnameIndex           = "httpRouteHostname"
 	tlsRouteHostnameIndex            = "tlsRouteHostname"
 	grpcRouteHostnameIndex           = "grpcRouteHostname"
@@ -41,6 +51,8 @@ const (
 	externalDnsHostnameAnnotationKey = "external-dns.alpha.kubernetes.io/hostname"
 	ignoreLabelKey                   = "k8s-gateway.dns/ignore"
 	resolveEndpointsAnnotationKey    = "k8s-gateway.dns/resolve-endpoints"
+	ttlAnnotationKey                 = "k8s-gateway.dns/ttl"
+	externalDnsTTLAnnotationKey      = "external-dns.alpha.kubernetes.io/ttl"
 )
 
 var (
@@ -49,22 +61,41 @@ var (
 
 // KubeController stores the current runtime configuration and cache
 type KubeController struct {
-	client      kubernetes.Interface
-	gwClient    gatewayClient.Interface
-	controllers []cache.SharedIndexInformer
-	hasSynced   bool
+	client   kubernetes.Interface
+	gwClient gatewayClient.Interface
+	// namespaces holds the namespaces to watch, or only core.NamespaceAll
+	namespaces []string
+	// namespaceFilter restricts the objects of all namespaces to the selected ones,
+	// nil without a namespace label selector
+	namespaceFilter *namespaceFilter
+	controllers     []cache.SharedIndexInformer
+	// resources holds the resource type of each controller, for metrics
+	resources []string
+	// services and endpointSlices are shared by every resource that looks up
+	// Services or EndpointSlices, see sharedServices
+	services       informers
+	endpointSlices informers
+
+	mu        sync.RWMutex
+	hasSynced bool
 }
 
-func newKubeController(ctx context.Context, c *kubernetes.Clientset, gw *gatewayClient.Clientset, originalGateway *Gateway) *KubeController {
+func newKubeController(ctx context.Context, c *kubernetes.Clientset, gw *gatewayClient.Clientset, namespaces []string, originalGateway *Gateway) *KubeController {
 	log.Infof("Building k8s_gateway controller")
 
 	ctrl := &KubeController{
-		client:   c,
-		gwClient: gw,
+		client:          c,
+		gwClient:        gw,
+		namespaces:      namespaces,
+		namespaceFilter: newNamespaceFilter(ctx, c, originalGateway.resourceFilters),
+	}
+	if ctrl.namespaceFilter != nil {
+		// selecting or deselecting a namespace adds or removes records, like any other change
+		ctrl.addController("Namespace", ctrl.namespaceFilter.selected)
 	}
 
 	configuredResources := dereferenceStrings(originalGateway.ConfiguredResources)
-	routingResources := []string{"HTTPRoute", "TLSRoute", "GRPCRoute"}
+	routingResources := []string{"Gateway", "HTTPRoute", "TLSRoute", "GRPCRoute", "TCPRoute", "UDPRoute"}
 
 	shouldInitGateway := false
 	for _, r := range routingResources {
@@ -75,39 +106,72 @@ func newKubeController(ctx context.Context, c *kubernetes.Clientset, gw *gateway
 	}
 
 	if crdExists(apiextensionsClient, "gatewayclasses.gateway.networking.k8s.io") && shouldInitGateway {
-		gatewayController := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc:  gatewayLister(ctx, ctrl.gwClient, core.NamespaceAll),
-				WatchFunc: gatewayWatcher(ctx, ctrl.gwClient, core.NamespaceAll),
-			},
-			&gatewayapi_v1.Gateway{},
-			defaultResyncPeriod,
-			cache.Indexers{gatewayUniqueIndex: gatewayIndexFunc},
-		)
-		ctrl.controllers = append(ctrl.controllers, gatewayController)
+		gatewayControllers := ctrl.forEachNamespace(func(ns string) cache.SharedIndexInformer {
+			return cache.NewSharedIndexInformer(
+				&cache.ListWatch{
+					ListFunc:  gatewayLister(ctx, ctrl.gwClient, ns),
+					WatchFunc: gatewayWatcher(ctx, ctrl.gwClient, ns),
+				},
+				&gatewayapi_v1.Gateway{},
+				defaultResyncPeriod,
+				cache.Indexers{
+					gatewayUniqueIndex:           gatewayIndexFunc,
+					gatewayListenerHostnameIndex: gatewayListenerHostnameIndexFunc,
+				},
+			)
+		})
+		ctrl.addController("Gateway", gatewayControllers...)
 		log.Infof("GatewayAPI controller initialized")
 
+		parents := parentInformers{gateways: gatewayControllers}
+		if crdServesVersion(apiextensionsClient, "listenersets.gateway.networking.k8s.io", "v1") {
+			parents.listenerSets = initializeListenerSetController(ctx, ctrl)
+			ctrl.addController("ListenerSet", parents.listenerSets...)
+			log.Infof("ListenerSet controller initialized")
+		}
+
+		if slices.Contains(configuredResources, "Gateway") {
+			if resource := originalGateway.lookupResource("Gateway"); resource != nil {
+				resource.lookup = lookupGatewayIndex(parents, originalGateway.resourceFilters.gatewayClasses)
+				resource.keys = parents.listenerHostnameKeys()
+			}
+		}
+
 		if slices.Contains(configuredResources, "HTTPRoute") && crdExists(apiextensionsClient, "httproutes.gateway.networking.k8s.io") {
 			if resource := originalGateway.lookupResource("HTTPRoute"); resource != nil {
-				httpRouteController := initializeHTTPRouteController(ctx, ctrl, gatewayController, originalGateway)
-				ctrl.controllers = append(ctrl.controllers, httpRouteController)
+				httpRouteControllers := initializeHTTPRouteController(ctx, ctrl, parents, originalGateway)
+				ctrl.addController("HTTPRoute", httpRouteControllers...)
 				log.Infof("HTTPRoute controller initialized")
 			}
 		}
 		if slices.Contains(configuredResources, "TLSRoute") && crdServesVersion(apiextensionsClient, "tlsroutes.gateway.networking.k8s.io", "v1") {
 			if resource := originalGateway.lookupResource("TLSRoute"); resource != nil {
-				tlsRouteController := initializeTLSRouteController(ctx, ctrl, gatewayController, originalGateway)
-				ctrl.controllers = append(ctrl.controllers, tlsRouteController)
+				tlsRouteControllers := initializeTLSRouteController(ctx, ctrl, parents, originalGateway)
+				ctrl.addController("TLSRoute", tlsRouteControllers...)
 				log.Infof("TLSRoute controller initialized")
 			}
 		}
 		if slices.Contains(configuredResources, "GRPCRoute") && crdExists(apiextensionsClient, "grpcroutes.gateway.networking.k8s.io") {
 			if resource := originalGateway.lookupResource("GRPCRoute"); resource != nil {
-				grpcRouteController := initializeGRPCRouteController(ctx, ctrl, gatewayController, originalGateway)
-				ctrl.controllers = append(ctrl.controllers, grpcRouteController)
+				grpcRouteControllers := initializeGRPCRouteController(ctx, ctrl, parents, originalGateway)
+				ctrl.addController("GRPCRoute", grpcRouteControllers...)
 				log.Infof("GRPCRoute controller initialized")
 			}
 		}
+		if slices.Contains(configuredResources, "TCPRoute") && crdServesVersion(apiextensionsClient, "tcproutes.gateway.networking.k8s.io", "v1alpha2") {
+			if resource := originalGateway.lookupResource("TCPRoute"); resource != nil {
+				tcpRouteControllers := initializeTCPRouteController(ctx, ctrl, parents, originalGateway)
+				ctrl.addController("TCPRoute", tcpRouteControllers...)
+				log.Infof("TCPRoute controller initialized")
+			}
+		}
+		if slices.Contains(configuredResources, "UDPRoute") && crdServesVersion(apiextensionsClient, "udproutes.gateway.networking.k8s.io", "v1alpha2") {
+			if resource := originalGateway.lookupResource("UDPRoute"); resource != nil {
+				udpRouteControllers := initializeUDPRouteController(ctx, ctrl, parents, originalGateway)
+				ctrl.addController("UDPRoute", udpRouteControllers...)
+				log.Infof("UDPRoute controller initialized")
+			}
+		}
 	}
 
 	for _, resourceName := range []string{"Ingress", "Service"} {
@@ -115,51 +179,32 @@ func newKubeController(ctx context.Context, c *kubernetes.Clientset, gw *gateway
 			if resource := originalGateway.lookupResource(resourceName); resource != nil {
 				switch resourceName {
 				case "Ingress":
-					ingressController := cache.NewSharedIndexInformer(
-						&cache.ListWatch{
-							ListFunc:  ingressLister(ctx, ctrl.client, core.NamespaceAll),
-							WatchFunc: ingressWatcher(ctx, ctrl.client, core.NamespaceAll),
-						},
-						&networking.Ingress{},
-						defaultResyncPeriod,
-						cache.Indexers{ingressHostnameIndex: ingressHostnameIndexFunc},
-					)
-					resource.lookup = lookupIngressIndex(ingressController, originalGateway.resourceFilters.ingressClasses)
-					ctrl.controllers = append(ctrl.controllers, ingressController)
-					log.Infof("Ingress controller initialized")
-
-				case "Service":
-					selectors := originalGateway.resourceFilters.serviceLabelSelectors
-					if len(selectors) == 0 {
-						selectors = []string{""}
-					}
-					var serviceControllers []cache.SharedIndexInformer
-					for _, sel := range selectors {
-						sc := cache.NewSharedIndexInformer(
+					ingressControllers := ctrl.forEachNamespace(func(ns string) cache.SharedIndexInformer {
+						return cache.NewSharedIndexInformer(
 							&cache.ListWatch{
-								ListFunc:  serviceLister(ctx, ctrl.client, core.NamespaceAll, sel),
-								WatchFunc: serviceWatcher(ctx, ctrl.client, core.NamespaceAll, sel),
+								ListFunc:  ingressLister(ctx, ctrl.client, ns),
+								WatchFunc: ingressWatcher(ctx, ctrl.client, ns),
 							},
-							&core.Service{},
+							&networking.Ingress{},
 							defaultResyncPeriod,
-							cache.Indexers{serviceHostnameIndex: serviceHostnameIndexFunc},
+							cache.Indexers{ingressHostnameIndex: ingressHostnameIndexFunc},
 						)
-						serviceControllers = append(serviceControllers, sc)
-						ctrl.controllers = append(ctrl.controllers, sc)
-					}
-
-					endpointSliceController := cache.NewSharedIndexInformer(
-						&cache.ListWatch{
-							ListFunc:  endpointSliceLister(ctx, ctrl.client, core.NamespaceAll),
-							WatchFunc: endpointSliceWatcher(ctx, ctrl.client, core.NamespaceAll),
-						},
-						&discovery.EndpointSlice{},
-						defaultResyncPeriod,
-						cache.Indexers{endpointSliceServiceIndex: endpointSliceServiceIndexFunc},
-					)
-					ctrl.controllers = append(ctrl.controllers, endpointSliceController)
-
-					resource.lookup = lookupServiceIndex(serviceControllers, endpointSliceController)
+					})
+					resource.lookup = lookupIngressIndex(ingressControllers, originalGateway.resourceFilters.ingressClasses)
+					resource.keys = listIndexKeys(ingressHostnameIndex, ingressControllers...)
+					ctrl.addController("Ingress", ingressControllers...)
+					log.Infof("Ingress controller initialized")
+
+				case "Service":
+					serviceControllers := ctrl.sharedServices(ctx, cache.Indexers{
+						serviceHostnameIndex: selectedServiceIndexFunc(originalGateway.resourceFilters.serviceLabelSelectors),
+					})
+					endpointSliceControllers := ctrl.sharedEndpointSlices(ctx, cache.Indexers{
+						endpointSliceServiceIndex: endpointSliceServiceIndexFunc,
+					})
+
+					resource.lookup = lookupServiceIndex(serviceControllers, endpointSliceControllers)
+					resource.keys = listIndexKeys(serviceHostnameIndex, serviceControllers...)
 					log.Infof("Service controller initialized")
 				}
 			}
@@ -167,6 +212,12 @@ func newKubeController(ctx context.Context, c *kubernetes.Clientset, gw *gateway
 	}
 
 	initializeDNSEndpointController(ctx, ctrl, originalGateway)
+	initializeIstioController(ctx, ctrl, originalGateway)
+	initializeOpenShiftRouteController(ctx, ctrl, originalGateway)
+	initializeTraefikController(ctx, ctrl, originalGateway)
+	initializeHTTPProxyController(ctx, ctrl, originalGateway)
+	initializeServiceImportController(ctx, ctrl, originalGateway)
+	initializePodController(ctx, ctrl, originalGateway)
 
 	if slices.Contains(dereferenceStrings(originalGateway.ConfiguredResources), "Node") {
 		if resource := originalGateway.lookupResource("Node"); resource != nil {
@@ -180,95 +231,234 @@ func newKubeController(ctx context.Context, c *kubernetes.Clientset, gw *gateway
 				cache.Indexers{nodeHostnameIndex: nodeHostnameIndexFunc},
 			)
 			resource.lookup = lookupNodeIndex(nodeController, core.NodeAddressType(originalGateway.nodeAddressType))
-			ctrl.controllers = append(ctrl.controllers, nodeController)
+			resource.keys = listIndexKeys(nodeHostnameIndex, nodeController)
+			ctrl.addController("Node", nodeController)
 			log.Infof("Node controller initialized")
 		}
 	}
 
+	// Any change may add or remove a published record
+	for _, controller := range ctrl.controllers {
+		if _, err := controller.AddEventHandler(originalGateway.eventHandler()); err != nil {
+			log.Warningf("failed to register event handler: %s", err)
+		}
+	}
+
+	// DNSSEC keys don't change any record, so the secret is watched separately
+	initializeDNSSECSecretController(ctx, ctrl, originalGateway)
+
 	return ctrl
 }
 
-func initializeHTTPRouteController(ctx context.Context, ctrl *KubeController, gatewayController cache.SharedIndexInformer, originalGateway *Gateway) cache.SharedIndexInformer {
-	httpRouteController := cache.NewSharedIndexInformer(
-		&cache.ListWatch{
-			ListFunc:  httpRouteLister(ctx, ctrl.gwClient, core.NamespaceAll),
-			WatchFunc: httpRouteWatcher(ctx, ctrl.gwClient, core.NamespaceAll),
-		},
-		&gatewayapi_v1.HTTPRoute{},
-		defaultResyncPeriod,
-		cache.Indexers{httpRouteHostnameIndex: httpRouteHostnameIndexFunc},
-	)
+func initializeHTTPRouteController(ctx context.Context, ctrl *KubeController, parents parentInformers, originalGateway *Gateway) informers {
+	httpRouteControllers := ctrl.forEachNamespace(func(ns string) cache.SharedIndexInformer {
+		return cache.NewSharedIndexInformer(
+			&cache.ListWatch{
+				ListFunc:  httpRouteLister(ctx, ctrl.gwClient, ns),
+				WatchFunc: httpRouteWatcher(ctx, ctrl.gwClient, ns),
+			},
+			&gatewayapi_v1.HTTPRoute{},
+			defaultResyncPeriod,
+			cache.Indexers{
+				httpRouteHostnameIndex: httpRouteHostnameIndexFunc,
+				routeParentIndex:       routeParentIndexFunc,
+			},
+		)
+	})
 	originalGateway.lookupResource("HTTPRoute").lookup = lookupHttpRouteIndex(
-		httpRouteController,
-		gatewayController,
+		httpRouteControllers,
+		parents,
 		originalGateway.resourceFilters.gatewayClasses,
+		originalGateway.resourceFilters.routeStatus,
 	)
-	return httpRouteController
-}
-
-func initializeTLSRouteController(ctx context.Context, ctrl *KubeController, gatewaycontroller cache.SharedIndexInformer, originalGateway *Gateway) cache.SharedIndexInformer {
-	tlsRouteController := cache.NewSharedIndexInformer(
-		&cache.ListWatch{
-			ListFunc:  tlsRouteLister(ctx, ctrl.gwClient, core.NamespaceAll),
-			WatchFunc: tlsRouteWatcher(ctx, ctrl.gwClient, core.NamespaceAll),
-		},
-		&gatewayapi_v1.TLSRoute{},
-		defaultResyncPeriod,
-		cache.Indexers{tlsRouteHostnameIndex: tlsRouteHostnameIndexFunc},
-	)
+	originalGateway.lookupResource("HTTPRoute").keys = routeIndexKeys(httpRouteHostnameIndex, httpRouteControllers, parents)
+	return httpRouteControllers
+}
+
+func initializeTLSRouteController(ctx context.Context, ctrl *KubeController, parents parentInformers, originalGateway *Gateway) informers {
+	tlsRouteControllers := ctrl.forEachNamespace(func(ns string) cache.SharedIndexInformer {
+		return cache.NewSharedIndexInformer(
+			&cache.ListWatch{
+				ListFunc:  tlsRouteLister(ctx, ctrl.gwClient, ns),
+				WatchFunc: tlsRouteWatcher(ctx, ctrl.gwClient, ns),
+			},
+			&gatewayapi_v1.TLSRoute{},
+			defaultResyncPeriod,
+			cache.Indexers{
+				tlsRouteHostnameIndex: tlsRouteHostnameIndexFunc,
+				routeParentIndex:      routeParentIndexFunc,
+			},
+		)
+	})
 	originalGateway.lookupResource("TLSRoute").lookup = lookupTLSRouteIndex(
-		tlsRouteController,
-		gatewaycontroller,
+		tlsRouteControllers,
+		parents,
 		originalGateway.resourceFilters.gatewayClasses,
+		originalGateway.resourceFilters.routeStatus,
 	)
-	return tlsRouteController
-}
-
-func initializeGRPCRouteController(ctx context.Context, ctrl *KubeController, gatewayController cache.SharedIndexInformer, originalGateway *Gateway) cache.SharedIndexInformer {
-	grpcRouteController := cache.NewSharedIndexInformer(
-		&cache.ListWatch{
-			ListFunc:  grpcRouteLister(ctx, ctrl.gwClient, core.NamespaceAll),
-			WatchFunc: grpcRouteWatcher(ctx, ctrl.gwClient, core.NamespaceAll),
-		},
-		&gatewayapi_v1.GRPCRoute{},
-		defaultResyncPeriod,
-		cache.Indexers{grpcRouteHostnameIndex: grpcRouteHostnameIndexFunc},
-	)
+	originalGateway.lookupResource("TLSRoute").keys = routeIndexKeys(tlsRouteHostnameIndex, tlsRouteControllers, parents)
+	return tlsRouteControllers
+}
+
+func initializeGRPCRouteController(ctx context.Context, ctrl *KubeController, parents parentInformers, originalGateway *Gateway) informers {
+	grpcRouteControllers := ctrl.forEachNamespace(func(ns string) cache.SharedIndexInformer {
+		return cache.NewSharedIndexInformer(
+			&cache.ListWatch{
+				ListFunc:  grpcRouteLister(ctx, ctrl.gwClient, ns),
+				WatchFunc: grpcRouteWatcher(ctx, ctrl.gwClient, ns),
+			},
+			&gatewayapi_v1.GRPCRoute{},
+			defaultResyncPeriod,
+			cache.Indexers{
+				grpcRouteHostnameIndex: grpcRouteHostnameIndexFunc,
+				routeParentIndex:       routeParentIndexFunc,
+			},
+		)
+	})
 	originalGateway.lookupResource("GRPCRoute").lookup = lookupGRPCRouteIndex(
-		grpcRouteController,
-		gatewayController,
+		grpcRouteControllers,
+		parents,
 		originalGateway.resourceFilters.gatewayClasses,
+		originalGateway.resourceFilters.routeStatus,
 	)
-	return grpcRouteController
+	originalGateway.lookupResource("GRPCRoute").keys = routeIndexKeys(grpcRouteHostnameIndex, grpcRouteControllers, parents)
+	return grpcRouteControllers
+}
+
+// addController adds the informers watching the given resource type
+func (ctrl *KubeController) addController(resource string, controllers ...cache.SharedIndexInformer) {
+	for _, controller := range controllers {
+		ctrl.controllers = append(ctrl.controllers, controller)
+		ctrl.resources = append(ctrl.resources, resource)
+	}
+}
+
+// updateObjectCounts sets the number of objects held per resource type
+func (ctrl *KubeController) updateObjectCounts() {
+	counts := make(map[string]int)
+	for i, controller := range ctrl.controllers {
+		counts[ctrl.resources[i]] += len(controller.GetStore().ListKeys())
+	}
+	for resource, count := range counts {
+		resourceObjects.WithLabelValues(resource).Set(float64(count))
+	}
+}
+
+// sharedServices returns the informers watching all Services in the watched
+// namespaces, with the given indexers added to them. They are created on first use
+// and shared by every resource, so that Services are listed, watched and cached
+// only once.
+func (ctrl *KubeController) sharedServices(ctx context.Context, indexers cache.Indexers) informers {
+	if ctrl.services == nil {
+		ctrl.services = ctrl.forEachNamespace(func(ns string) cache.SharedIndexInformer {
+			return cache.NewSharedIndexInformer(
+				&cache.ListWatch{
+					ListFunc:  serviceLister(ctx, ctrl.client, ns, ""),
+					WatchFunc: serviceWatcher(ctx, ctrl.client, ns, ""),
+				},
+				&core.Service{},
+				defaultResyncPeriod,
+				cache.Indexers{},
+			)
+		})
+		ctrl.addController("Service", ctrl.services...)
+	}
+	ctrl.services.addIndexers(indexers)
+	return ctrl.services
+}
+
+// sharedEndpointSlices returns the informers watching all EndpointSlices in the
+// watched namespaces, like sharedServices
+func (ctrl *KubeController) sharedEndpointSlices(ctx context.Context, indexers cache.Indexers) informers {
+	if ctrl.endpointSlices == nil {
+		ctrl.endpointSlices = ctrl.forEachNamespace(func(ns string) cache.SharedIndexInformer {
+			return cache.NewSharedIndexInformer(
+				&cache.ListWatch{
+					ListFunc:  endpointSliceLister(ctx, ctrl.client, ns),
+					WatchFunc: endpointSliceWatcher(ctx, ctrl.client, ns),
+				},
+				&discovery.EndpointSlice{},
+				defaultResyncPeriod,
+				cache.Indexers{},
+			)
+		})
+		ctrl.addController("EndpointSlice", ctrl.endpointSlices...)
+	}
+	ctrl.endpointSlices.addIndexers(indexers)
+	return ctrl.endpointSlices
 }
 
-func (ctrl *KubeController) run() {
-	stopCh := make(chan struct{})
-	defer close(stopCh)
+// listIndexKeys returns a function listing all keys of the given index across informers
+func listIndexKeys(index string, informers ...cache.SharedIndexInformer) func() []string {
+	return func() (keys []string) {
+		for _, informer := range informers {
+			keys = append(keys, informer.GetIndexer().ListIndexFuncValues(index)...)
+		}
+		return keys
+	}
+}
 
+// routeIndexKeys returns a function listing the hostnames of routes and the hostnames
+// of Gateway and ListenerSet listeners, which routes without hostnames inherit
+func routeIndexKeys(index string, routes informers, parents parentInformers) func() []string {
+	routeKeys := listIndexKeys(index, routes...)
+	listenerKeys := parents.listenerHostnameKeys()
+	return func() []string {
+		return append(routeKeys(), listenerKeys()...)
+	}
+}
+
+// run starts all informers and waits for them to sync. They are stopped once ctx is done.
+func (ctrl *KubeController) run(ctx context.Context) {
 	var synced []cache.InformerSynced
 
 	log.Infof("Starting k8s_gateway controller")
-	for _, ctrl := range ctrl.controllers {
-		go ctrl.Run(stopCh)
-		synced = append(synced, ctrl.HasSynced)
+	perResource := make(map[string][]cache.InformerSynced)
+	for i, controller := range ctrl.controllers {
+		go controller.Run(ctx.Done())
+		synced = append(synced, controller.HasSynced)
+		perResource[ctrl.resources[i]] = append(perResource[ctrl.resources[i]], controller.HasSynced)
+	}
+	for resource, synced := range perResource {
+		informerSynced.WithLabelValues(resource).Set(0)
+		go func() {
+			if cache.WaitForCacheSync(ctx.Done(), synced...) {
+				log.Infof("Synced %s resources", resource)
+				informerSynced.WithLabelValues(resource).Set(1)
+			}
+		}()
 	}
 
 	log.Infof("Waiting for controllers to sync")
-	if !cache.WaitForCacheSync(stopCh, synced...) {
-		ctrl.hasSynced = false
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
+		log.Warningf("Stopped before all resources synced, still waiting for: %v", ctrl.unsynced())
+		return
 	}
 	log.Infof("Synced all required resources")
-	ctrl.hasSynced = true
 
-	<-stopCh
+	ctrl.mu.Lock()
+	ctrl.hasSynced = true
+	ctrl.mu.Unlock()
 }
 
 // HasSynced returns true if all controllers have been synced
 func (ctrl *KubeController) HasSynced() bool {
+	ctrl.mu.RLock()
+	defer ctrl.mu.RUnlock()
 	return ctrl.hasSynced
 }
 
+// unsynced returns the resource types whose informers have not synced yet
+func (ctrl *KubeController) unsynced() (resources []string) {
+	for i, controller := range ctrl.controllers {
+		if !controller.HasSynced() && !slices.Contains(resources, ctrl.resources[i]) {
+			resources = append(resources, ctrl.resources[i])
+		}
+	}
+	slices.Sort(resources)
+	return resources
+}
+
 // RunKubeController kicks off the k8s controllers
 func (gw *Gateway) RunKubeController(ctx context.Context) error {
 	config, err := gw.getClientConfig()
@@ -296,12 +486,57 @@ func (gw *Gateway) RunKubeController(ctx context.Context) error {
 		log.Warningf("failed to build external-dns REST client: %s, ignoring and continuing execution", err)
 	}
 
-	gw.Controller = newKubeController(ctx, kubeClient, gwAPIClient, gw)
-	go gw.Controller.run()
+	istioCRDClient, err = newIstioRESTClient(config)
+	if err != nil {
+		log.Warningf("failed to build Istio REST client: %s, ignoring and continuing execution", err)
+	}
+
+	openshiftCRDClient, err = newOpenShiftRESTClient(config)
+	if err != nil {
+		log.Warningf("failed to build OpenShift REST client: %s, ignoring and continuing execution", err)
+	}
+
+	traefikCRDClient, err = newTraefikRESTClient(config)
+	if err != nil {
+		log.Warningf("failed to build Traefik REST client: %s, ignoring and continuing execution", err)
+	}
+
+	contourCRDClient, err = newContourRESTClient(config)
+	if err != nil {
+		log.Warningf("failed to build Contour REST client: %s, ignoring and continuing execution", err)
+	}
+
+	mcsCRDClient, err = newMCSRESTClient(config)
+	if err != nil {
+		log.Warningf("failed to build MCS API REST client: %s, ignoring and continuing execution", err)
+	}
+
+	gw.Controller = newKubeController(ctx, kubeClient, gwAPIClient, watchedNamespaces(gw.resourceFilters), gw)
+	go gw.Controller.run(ctx)
 
 	return nil
 }
 
+// newCRDRESTClient returns a REST client for the resources of a group version
+// added to a scheme by addToScheme, without their clientset.
+func newCRDRESTClient(config *rest.Config, gv schema.GroupVersion, addToScheme func(*runtime.Scheme) error) (rest.Interface, error) {
+	scheme := runtime.NewScheme()
+	if err := addToScheme(scheme); err != nil {
+		return nil, err
+	}
+	cfgCopy := *config
+	cfgCopy.GroupVersion = &gv
+	cfgCopy.APIPath = "/apis"
+	cfgCopy.NegotiatedSerializer = serializer.WithoutConversionCodecFactory{
+		CodecFactory: serializer.NewCodecFactory(scheme),
+	}
+	client, err := rest.RESTClientFor(&cfgCopy)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
 func crdExists(clientset *apiextensionsclientset.Clientset, crdName string) bool {
 	crd, err := clientset.ApiextensionsV1().CustomResourceDefinitions().Get(context.TODO(), crdName, metav1.GetOptions{})
 	if err != nil {
@@ -347,6 +582,24 @@ func crdServesVersion(clientset *apiextensionsclientset.Clientset, crdName, vers
 	return false
 }
 
+// apiServesResource returns true if the API server serves the resource in the given
+// group version, e.g. for aggregated APIs that aren't backed by a CRD.
+func apiServesResource(c kubernetes.Interface, groupVersion, resource string) bool {
+	resources, err := c.Discovery().ServerResourcesForGroupVersion(groupVersion)
+	if err != nil {
+		log.Warningf("error discovering api %s, error: %s", groupVersion, err.Error())
+		return false
+	}
+	for _, r := range resources.APIResources {
+		if r.Name == resource {
+			log.Infof("api %s/%s found", groupVersion, resource)
+			return true
+		}
+	}
+	log.Warningf("api %s found but does not serve %s", groupVersion, resource)
+	return false
+}
+
 func (gw *Gateway) getClientConfig() (*rest.Config, error) {
 	if gw.configFile != "" {
 		overrides := &clientcmd.ConfigOverrides{}
@@ -467,6 +720,70 @@ func gatewayIndexFunc(obj interface{}) ([]string, error) {
 	return []string{fmt.Sprintf("%s/%s", metaObj.GetNamespace(), metaObj.GetName())}, nil
 }
 
+// gatewayListenerHostnameIndexFunc indexes a Gateway by the hostnames of its listeners
+func gatewayListenerHostnameIndexFunc(obj interface{}) ([]string, error) {
+	gw, ok := obj.(*gatewayapi_v1.Gateway)
+	if !ok {
+		return []string{}, nil
+	}
+
+	var hostnames []string
+	for _, listener := range gw.Spec.Listeners {
+		if listener.Hostname == nil || *listener.Hostname == "" {
+			continue
+		}
+		hostname := strings.ToLower(string(*listener.Hostname))
+		if !slices.Contains(hostnames, hostname) {
+			hostnames = append(hostnames, hostname)
+		}
+	}
+	return hostnames, nil
+}
+
+// routeParentIndexFunc indexes a route by the Gateways and ListenerSets it references,
+// see routeParent.indexKey
+func routeParentIndexFunc(obj interface{}) ([]string, error) {
+	var objMeta metav1.ObjectMeta
+	var spec gatewayapi_v1.CommonRouteSpec
+	switch route := obj.(type) {
+	case *gatewayapi_v1.HTTPRoute:
+		objMeta, spec = route.ObjectMeta, route.Spec.CommonRouteSpec
+	case *gatewayapi_v1.TLSRoute:
+		objMeta, spec = route.ObjectMeta, route.Spec.CommonRouteSpec
+	case *gatewayapi_v1.GRPCRoute:
+		objMeta, spec = route.ObjectMeta, route.Spec.CommonRouteSpec
+	case *gatewayapi_v1alpha2.TCPRoute:
+		objMeta, spec = route.ObjectMeta, route.Spec.CommonRouteSpec
+	case *gatewayapi_v1alpha2.UDPRoute:
+		objMeta, spec = route.ObjectMeta, route.Spec.CommonRouteSpec
+	default:
+		return []string{}, nil
+	}
+
+	if checkIgnoreLabel(objMeta.Labels) {
+		return []string{}, nil
+	}
+
+	var keys []string
+	for _, ref := range spec.ParentRefs {
+		var kind gatewayapi_v1.Kind
+		switch {
+		case isGatewayRef(ref):
+			kind = "Gateway"
+		case isListenerSetRef(ref):
+			kind = "ListenerSet"
+		default:
+			continue
+		}
+		ns := objMeta.Namespace
+		if ref.Namespace != nil {
+			ns = string(*ref.Namespace)
+		}
+		keys = append(keys, parentIndexKey(kind, ns, string(ref.Name)))
+	}
+	return keys, nil
+}
+
 func httpRouteHostnameIndexFunc(obj interface{}) ([]string, error) {
 	httpRoute, ok := obj.(*gatewayapi_v1.HTTPRoute)
 	if !ok {
@@ -558,7 +875,7 @@ func serviceHostnameIndexFunc(obj interface{}) ([]string, error) {
 		return []string{}, nil
 	}
 
-	if !isLoadBalancerService(service) && !resolveEndpointsRequested(service) {
+	if !isLoadBalancerService(service) && !resolveEndpointsRequested(service) && !isExternalNameService(service) {
 		return []string{}, nil
 	}
 
@@ -570,17 +887,45 @@ func serviceHostnameIndexFunc(obj interface{}) ([]string, error) {
 				log.Debugf("Adding index %s for service %s", hostname, service.Name)
 			}
 		}
-	} else {
+	} else if !isExternalNameService(service) {
 		hostnames = []string{service.Name + "." + service.Namespace}
 	}
 
 	return hostnames, nil
 }
 
+// selectedServiceIndexFunc indexes the Services matching any of the serviceLabelSelectors
+// like serviceHostnameIndexFunc, and all Services if none are configured
+func selectedServiceIndexFunc(selectors []string) cache.IndexFunc {
+	var parsed []labels.Selector
+	for _, selector := range selectors {
+		// validated when the plugin is set up
+		if sel, err := labels.Parse(selector); err == nil {
+			parsed = append(parsed, sel)
+		}
+	}
+	return func(obj interface{}) ([]string, error) {
+		service, ok := obj.(*core.Service)
+		if !ok {
+			return []string{}, nil
+		}
+		if len(parsed) > 0 && !slices.ContainsFunc(parsed, func(sel labels.Selector) bool { return sel.Matches(labels.Set(service.Labels)) }) {
+			return []string{}, nil
+		}
+		return serviceHostnameIndexFunc(obj)
+	}
+}
+
 func isLoadBalancerService(service *core.Service) bool {
 	return service.Spec.Type == core.ServiceTypeLoadBalancer
 }
 
+// isExternalNameService reports whether a Service is an alias of spec.externalName.
+// These are only published under their hostname annotations.
+func isExternalNameService(service *core.Service) bool {
+	return service.Spec.Type == core.ServiceTypeExternalName && service.Spec.ExternalName != ""
+}
+
 // resolveEndpointsRequested reports whether a Service has opted in to
 // endpoint-based DNS resolution via the k8s-gateway.dns/resolve-endpoints
 // annotation. When set, the Service's backing EndpointSlice IPs are returned.
@@ -615,6 +960,26 @@ func splitHostnameAnnotation(annotation string) []string {
 	return strings.Split(strings.ReplaceAll(annotation, " ", ""), ",")
 }
 
+// annotationTTL returns the TTL set by the ttl annotation of an object, either in
+// seconds or as a duration like 5m, 0 if it's not set or invalid.
+func annotationTTL(obj metav1.ObjectMeta) uint32 {
+	for _, key := range []string{ttlAnnotationKey, externalDnsTTLAnnotationKey} {
+		value, ok := obj.Annotations[key]
+		if !ok {
+			continue
+		}
+		if ttl, err := strconv.ParseUint(value, 10, 32); err == nil {
+			return uint32(ttl)
+		}
+		if d, err := time.ParseDuration(value); err == nil && d >= time.Second && d.Seconds() <= math.MaxUint32 {
+			return uint32(d.Seconds())
+		}
+		log.Warningf("Ignoring invalid TTL %q of %s/%s", value, obj.Namespace, obj.Name)
+		return 0
+	}
+	return 0
+}
+
 func checkServiceAnnotations(service *core.Service, annotations ...string) (string, bool) {
 	for _, annotation := range annotations {
 		if annotationValue, exists := service.Annotations[annotation]; exists {
@@ -638,59 +1003,75 @@ func checkDomainValid(domain string) bool {
 	return false
 }
 
-func lookupServiceIndex(controllers []cache.SharedIndexInformer, endpointSliceController cache.SharedIndexInformer) func([]string) (results []netip.Addr, raws []string) {
-	return func(indexKeys []string) (result []netip.Addr, raw []string) {
+func lookupServiceIndex(controllers, endpointSliceControllers informers) func([]string) lookupResult {
+	return func(indexKeys []string) (result lookupResult) {
 		seen := make(map[string]struct{})
 		var objs []interface{}
-		for _, ctrl := range controllers {
-			for _, key := range indexKeys {
-				obj, _ := ctrl.GetIndexer().ByIndex(serviceHostnameIndex, strings.ToLower(key))
-				for _, o := range obj {
-					svc, ok := o.(*core.Service)
-					if !ok {
-						continue
-					}
-					nsName := svc.Namespace + "/" + svc.Name
-					if _, dup := seen[nsName]; dup {
-						continue
-					}
-					seen[nsName] = struct{}{}
-					objs = append(objs, o)
+		for _, key := range indexKeys {
+			for _, o := range controllers.byIndex(serviceHostnameIndex, strings.ToLower(key)) {
+				svc, ok := o.(*core.Service)
+				if !ok {
+					continue
+				}
+				nsName := svc.Namespace + "/" + svc.Name
+				if _, dup := seen[nsName]; dup {
+					continue
 				}
+				seen[nsName] = struct{}{}
+				objs = append(objs, o)
 			}
 		}
 		log.Debugf("Found %d matching Service objects", len(objs))
 		for _, obj := range objs {
 			service, _ := obj.(*core.Service)
+			result.lowerTTL(annotationTTL(service.ObjectMeta))
+			result.observe(service)
+
+			if isExternalNameService(service) {
+				// answered with a CNAME like a DNSEndpoint, never resolved by the plugin
+				result.hostnames = append(result.hostnames, strings.TrimSuffix(service.Spec.ExternalName, "."))
+				result.alias = true
+				continue
+			}
 
 			if resolveEndpointsRequested(service) {
-				result = append(result, endpointSliceAddresses(endpointSliceController, service)...)
+				result.observeAll(endpointSliceControllers.byIndex(endpointSliceServiceIndex, service.Namespace+"/"+service.Name))
+				result.addrs = append(result.addrs, endpointSliceAddresses(endpointSliceControllers, service)...)
+				result.ports = append(result.ports, endpointSlicePorts(endpointSliceControllers, service)...)
 				continue
 			}
 
+			result.ports = append(result.ports, fetchServicePorts(service.Spec.Ports)...)
+
 			if len(service.Spec.ExternalIPs) > 0 {
 				for _, ip := range service.Spec.ExternalIPs {
-					result = append(result, netip.MustParseAddr(ip))
+					result.addrs = append(result.addrs, netip.MustParseAddr(ip))
 				}
 				// in case externalIPs are defined, ignoring status field completely
 				return
 			}
 
-			result = append(result, fetchServiceLoadBalancerIPs(service.Status.LoadBalancer.Ingress)...)
+			addrs, hostnames := fetchServiceLoadBalancerIPs(service.Status.LoadBalancer.Ingress)
+			result.addrs = append(result.addrs, addrs...)
+			result.hostnames = append(result.hostnames, hostnames...)
 		}
 		return
 	}
 }
 
 // endpointSliceAddresses returns the ready endpoint IPs from all EndpointSlices
-// owned by the given Service. Endpoints whose Ready condition is explicitly
-// false are excluded; a nil Ready condition is treated as ready per the
-// EndpointSlice spec.
-func endpointSliceAddresses(endpointSliceController cache.SharedIndexInformer, service *core.Service) (result []netip.Addr) {
+// owned by the given Service.
+func endpointSliceAddresses(endpointSliceControllers informers, service *core.Service) []netip.Addr {
 	endpointSliceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
-	endpointSliceObjs, _ := endpointSliceController.GetIndexer().ByIndex(endpointSliceServiceIndex, endpointSliceKey)
+	endpointSliceObjs := endpointSliceControllers.byIndex(endpointSliceServiceIndex, endpointSliceKey)
 	log.Debugf("Found %d EndpointSlices for service %s", len(endpointSliceObjs), endpointSliceKey)
+	return readyEndpointAddresses(endpointSliceObjs)
+}
 
+// readyEndpointAddresses returns the ready endpoint IPs of the given EndpointSlices.
+// Endpoints whose Ready condition is explicitly false are excluded; a nil Ready
+// condition is treated as ready per the EndpointSlice spec.
+func readyEndpointAddresses(endpointSliceObjs []interface{}) (result []netip.Addr) {
 	seen := make(map[netip.Addr]struct{})
 	for _, esObj := range endpointSliceObjs {
 		endpointSlice, _ := esObj.(*discovery.EndpointSlice)
@@ -715,88 +1096,236 @@ func endpointSliceAddresses(endpointSliceController cache.SharedIndexInformer, s
 	return
 }
 
-func lookupHttpRouteIndex(http, gw cache.SharedIndexInformer, gwclasses []string) func([]string) (results []netip.Addr, raws []string) {
-	return func(indexKeys []string) (result []netip.Addr, raw []string) {
-		var objs []interface{}
-		for _, key := range indexKeys {
-			obj, _ := http.GetIndexer().ByIndex(httpRouteHostnameIndex, strings.ToLower(key))
-			objs = append(objs, obj...)
+// endpointSlicePorts returns the named ports of all EndpointSlices owned by the
+// given Service. Clients talk to the endpoints directly, so these are the target
+// ports rather than the ports of the Service itself.
+func endpointSlicePorts(endpointSliceControllers informers, service *core.Service) (result []servicePort) {
+	endpointSliceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+	endpointSliceObjs := endpointSliceControllers.byIndex(endpointSliceServiceIndex, endpointSliceKey)
+
+	seen := make(map[servicePort]struct{})
+	for _, esObj := range endpointSliceObjs {
+		endpointSlice, _ := esObj.(*discovery.EndpointSlice)
+		for _, port := range endpointSlice.Ports {
+			if port.Name == nil || *port.Name == "" || port.Port == nil {
+				continue
+			}
+			protocol := core.ProtocolTCP
+			if port.Protocol != nil {
+				protocol = *port.Protocol
+			}
+			p := servicePort{name: *port.Name, protocol: string(protocol), port: uint16(*port.Port)}
+			if _, dup := seen[p]; dup {
+				continue
+			}
+			seen[p] = struct{}{}
+			result = append(result, p)
 		}
+	}
+	return
+}
+
+// fetchServicePorts returns the named ports of a Service. Unnamed ports can't be
+// addressed by an SRV query and are skipped.
+func fetchServicePorts(ports []core.ServicePort) (results []servicePort) {
+	for _, port := range ports {
+		if port.Name == "" {
+			continue
+		}
+		protocol := port.Protocol
+		if protocol == "" {
+			protocol = core.ProtocolTCP
+		}
+		results = append(results, servicePort{name: port.Name, protocol: string(protocol), port: uint16(port.Port)})
+	}
+	return
+}
+
+func lookupHttpRouteIndex(http informers, parents parentInformers, gwclasses []string, checkStatus bool) func([]string) lookupResult {
+	return func(indexKeys []string) (result lookupResult) {
+		objs := routesByHostname(http, parents, httpRouteHostnameIndex, indexKeys)
 		log.Debugf("Found %d matching httpRoute objects", len(objs))
 
 		for _, obj := range objs {
 			httpRoute, _ := obj.(*gatewayapi_v1.HTTPRoute)
-			result = append(result, lookupGateways(gw, httpRoute.Spec.ParentRefs, httpRoute.Namespace, gwclasses)...)
+			route := attachedRoute{
+				namespace:  httpRoute.Namespace,
+				parentRefs: httpRoute.Spec.ParentRefs,
+				hostnames:  httpRoute.Spec.Hostnames,
+				status:     statusToCheck(checkStatus, &httpRoute.Status.RouteStatus),
+			}
+			result = result.merge(withRouteTTL(lookupGateways(parents, route, indexKeys, gwclasses, httpRouteService), httpRoute.ObjectMeta))
 		}
 		return
 	}
 }
 
-func lookupTLSRouteIndex(tls, gw cache.SharedIndexInformer, gwclasses []string) func([]string) (results []netip.Addr, raws []string) {
-	return func(indexKeys []string) (result []netip.Addr, raw []string) {
-		var objs []interface{}
-		for _, key := range indexKeys {
-			obj, _ := tls.GetIndexer().ByIndex(tlsRouteHostnameIndex, strings.ToLower(key))
-			objs = append(objs, obj...)
-		}
+func lookupTLSRouteIndex(tls informers, parents parentInformers, gwclasses []string, checkStatus bool) func([]string) lookupResult {
+	return func(indexKeys []string) (result lookupResult) {
+		objs := routesByHostname(tls, parents, tlsRouteHostnameIndex, indexKeys)
 		log.Debugf("Found %d matching tlsRoute objects", len(objs))
 
 		for _, obj := range objs {
 			tlsRoute, _ := obj.(*gatewayapi_v1.TLSRoute)
-			result = append(result, lookupGateways(gw, tlsRoute.Spec.ParentRefs, tlsRoute.Namespace, gwclasses)...)
+			route := attachedRoute{
+				namespace:  tlsRoute.Namespace,
+				parentRefs: tlsRoute.Spec.ParentRefs,
+				hostnames:  tlsRoute.Spec.Hostnames,
+				status:     statusToCheck(checkStatus, &tlsRoute.Status.RouteStatus),
+			}
+			result = result.merge(withRouteTTL(lookupGateways(parents, route, indexKeys, gwclasses, tlsRouteService), tlsRoute.ObjectMeta))
 		}
 		return
 	}
 }
 
-func lookupGRPCRouteIndex(grpc, gw cache.SharedIndexInformer, gwclasses []string) func([]string) (results []netip.Addr, raws []string) {
-	return func(indexKeys []string) (result []netip.Addr, raw []string) {
-		var objs []interface{}
-		for _, key := range indexKeys {
-			obj, _ := grpc.GetIndexer().ByIndex(grpcRouteHostnameIndex, strings.ToLower(key))
-			objs = append(objs, obj...)
-		}
+func lookupGRPCRouteIndex(grpc informers, parents parentInformers, gwclasses []string, checkStatus bool) func([]string) lookupResult {
+	return func(indexKeys []string) (result lookupResult) {
+		objs := routesByHostname(grpc, parents, grpcRouteHostnameIndex, indexKeys)
 		log.Debugf("Found %d matching grpcRoute objects", len(objs))
 
 		for _, obj := range objs {
 			grpcRoute, _ := obj.(*gatewayapi_v1.GRPCRoute)
-			result = append(result, lookupGateways(gw, grpcRoute.Spec.ParentRefs, grpcRoute.Namespace, gwclasses)...)
+			route := attachedRoute{
+				namespace:  grpcRoute.Namespace,
+				parentRefs: grpcRoute.Spec.ParentRefs,
+				hostnames:  grpcRoute.Spec.Hostnames,
+				status:     statusToCheck(checkStatus, &grpcRoute.Status.RouteStatus),
+			}
+			result = result.merge(withRouteTTL(lookupGateways(parents, route, indexKeys, gwclasses, grpcRouteService), grpcRoute.ObjectMeta))
 		}
 		return
 	}
 }
 
-func lookupGateways(gw cache.SharedIndexInformer, refs []gatewayapi_v1.ParentReference, ns string, gwclasses []string) (result []netip.Addr) {
-	for _, gwRef := range refs {
+// attachedRoute holds the parts of a route lookupGateways needs to find the
+// listeners serving it
+type attachedRoute struct {
+	namespace  string
+	parentRefs []gatewayapi_v1.ParentReference
+	hostnames  []gatewayapi_v1.Hostname
+	// status is only checked if set, see statusToCheck
+	status *gatewayapi_v1.RouteStatus
+}
 
-		if gwRef.Namespace != nil {
-			ns = string(*gwRef.Namespace)
+// routesByHostname returns the routes indexed by one of the index keys, as well as
+// the routes attached to Gateways or ListenerSets with a listener for one of the
+// keys. The latter may inherit the listener hostname, lookupGateways sorts out the
+// others.
+func routesByHostname(routes informers, parents parentInformers, index string, indexKeys []string) (objs []interface{}) {
+	add := func(found []interface{}) {
+		for _, obj := range found {
+			if !slices.Contains(objs, obj) {
+				objs = append(objs, obj)
+			}
 		}
-		gwKey := fmt.Sprintf("%s/%s", ns, gwRef.Name)
+	}
+	for _, key := range indexKeys {
+		add(routes.byIndex(index, strings.ToLower(key)))
+		for _, parent := range parents.byListenerHostname([]string{key}) {
+			add(routes.byIndex(routeParentIndex, parent.indexKey()))
+		}
+	}
+	return objs
+}
 
-		gwObjs, _ := gw.GetIndexer().ByIndex(gatewayUniqueIndex, gwKey)
-		log.Debugf("Found %d matching gateway objects", len(gwObjs))
+// lookupGateways returns the addresses of the Gateways the route is attached to,
+// directly or through a ListenerSet, with a listener serving it under one of the
+// index keys. If the route status is set, only parents that accepted the route and
+// are programmed are used.
+func lookupGateways(parents parentInformers, route attachedRoute, indexKeys []string, gwclasses []string, service routeService) (result lookupResult) {
+	routeNamespace, status := route.namespace, route.status
+	for _, ref := range route.parentRefs {
+		if status != nil && !routeAccepted(status, ref, routeNamespace) {
+			log.Debugf("Skipping parent %s, the route hasn't been accepted", ref.Name)
+			continue
+		}
 
-		for _, gwObj := range gwObjs {
-			gw, _ := gwObj.(*gatewayapi_v1.Gateway)
+		for _, parent := range parents.resolve(ref, routeNamespace) {
+			gw := parent.gateway
 
 			if len(gwclasses) > 0 && !slices.Contains(gwclasses, string(gw.Spec.GatewayClassName)) {
 				log.Debugf("Skipping gateway of '%s' gatewayClass", string(gw.Spec.GatewayClassName))
 				continue
 			}
 
-			result = append(result, fetchGatewayIPs(gw)...)
+			listeners := matchingListeners(parent, ref, routeNamespace, service)
+			if status != nil {
+				if !parent.programmed {
+					log.Debugf("Skipping %s %s/%s, it isn't programmed", parent.kind, parent.object.Namespace, parent.object.Name)
+					continue
+				}
+				listeners = programmedListeners(parent, listeners)
+			}
+			listeners = servingListeners(listeners, route.hostnames, indexKeys)
+			if len(listeners) == 0 {
+				log.Debugf("Skipping %s %s/%s, none of its listeners serves the route under %v", parent.kind, parent.object.Namespace, parent.object.Name, indexKeys)
+				continue
+			}
+
+			addrs, hostnames := fetchGatewayIPs(gw)
+			result.lowerTTL(annotationTTL(gw.ObjectMeta))
+			result.observe(gw)
+			result.observe(&parent.object)
+			result.addrs = append(result.addrs, addrs...)
+			result.hostnames = append(result.hostnames, hostnames...)
+			// a Gateway without any address doesn't serve the route yet
+			if len(addrs) > 0 || len(hostnames) > 0 {
+				result.records = append(result.records, httpsRecords(listeners, service, addrs)...)
+				result.ports = append(result.ports, listenerPorts(listeners, service)...)
+			}
 		}
 	}
 	return
 }
 
-func lookupIngressIndex(ctrl cache.SharedIndexInformer, ingclasses []string) func([]string) (results []netip.Addr, raws []string) {
-	return func(indexKeys []string) (result []netip.Addr, raw []string) {
+// lookupGatewayIndex returns the addresses of the Gateways with a listener for one
+// of the index keys, either of their own or of one of their ListenerSets, whether or
+// not any route is attached to it
+func lookupGatewayIndex(parents parentInformers, gwclasses []string) func([]string) lookupResult {
+	return func(indexKeys []string) (result lookupResult) {
+		matches := parents.byListenerHostname(indexKeys)
+		log.Debugf("Found %d matching gateway and listenerSet objects", len(matches))
+
+		for _, parent := range matches {
+			gw := parent.gateway
+
+			// the index is shared with the route lookups, which ignore the label on Gateways
+			if checkIgnoreLabel(parent.object.Labels) || checkIgnoreLabel(gw.Labels) {
+				log.Debugf("Ignoring %s %s due to %s label", parent.kind, parent.object.Name, ignoreLabelKey)
+				continue
+			}
+			if len(gwclasses) > 0 && !slices.Contains(gwclasses, string(gw.Spec.GatewayClassName)) {
+				log.Debugf("Skipping gateway of '%s' gatewayClass", string(gw.Spec.GatewayClassName))
+				continue
+			}
+
+			addrs, hostnames := fetchGatewayIPs(gw)
+			result.lowerTTL(annotationTTL(gw.ObjectMeta))
+			result.observe(gw)
+			result.observe(&parent.object)
+			result.addrs = append(result.addrs, addrs...)
+			result.hostnames = append(result.hostnames, hostnames...)
+		}
+		return
+	}
+}
+
+// withRouteTTL overrides the TTL of the Gateways a route is attached to with the
+// TTL annotated on the route itself, if any, and records the route's resourceVersion.
+func withRouteTTL(result lookupResult, route metav1.ObjectMeta) lookupResult {
+	result.observe(&route)
+	if ttl := annotationTTL(route); ttl != 0 {
+		result.ttl = ttl
+	}
+	return result
+}
+
+func lookupIngressIndex(ctrl informers, ingclasses []string) func([]string) lookupResult {
+	return func(indexKeys []string) (result lookupResult) {
 		var objs []interface{}
 		for _, key := range indexKeys {
-			obj, _ := ctrl.GetIndexer().ByIndex(ingressHostnameIndex, strings.ToLower(key))
-			objs = append(objs, obj...)
+			objs = append(objs, ctrl.byIndex(ingressHostnameIndex, strings.ToLower(key))...)
 		}
 		log.Debugf("Found %d matching Ingress objects", len(objs))
 		for _, obj := range objs {
@@ -807,14 +1336,19 @@ func lookupIngressIndex(ctrl cache.SharedIndexInformer, ingclasses []string) fun
 				continue
 			}
 
-			result = append(result, fetchIngressLoadBalancerIPs(ingress.Status.LoadBalancer.Ingress)...)
+			addrs, hostnames := fetchIngressLoadBalancerIPs(ingress.Status.LoadBalancer.Ingress)
+			result.lowerTTL(annotationTTL(ingress.ObjectMeta))
+			result.observe(ingress)
+			result.addrs = append(result.addrs, addrs...)
+			result.hostnames = append(result.hostnames, hostnames...)
 		}
 
 		return
 	}
 }
 
-func fetchGatewayIPs(gw *gatewayapi_v1.Gateway) (results []netip.Addr) {
+// fetchGatewayIPs returns the IP addresses and hostnames a Gateway reports in its status.
+func fetchGatewayIPs(gw *gatewayapi_v1.Gateway) (results []netip.Addr, hostnames []string) {
 	for _, addr := range gw.Status.Addresses {
 		if *addr.Type == gatewayapi_v1.IPAddressType {
 			addr, err := netip.ParseAddr(addr.Value)
@@ -826,37 +1360,16 @@ func fetchGatewayIPs(gw *gatewayapi_v1.Gateway) (results []netip.Addr) {
 		}
 
 		if *addr.Type == gatewayapi_v1.HostnameAddressType {
-			ips, err := net.LookupIP(addr.Value)
-			if err != nil {
-				continue
-			}
-			for _, ip := range ips {
-				addr, err := netip.ParseAddr(ip.String())
-				if err != nil {
-					continue
-				}
-				results = append(results, addr)
-			}
+			hostnames = append(hostnames, addr.Value)
 		}
 	}
 	return
 }
 
-func fetchServiceLoadBalancerIPs(ingresses []core.LoadBalancerIngress) (results []netip.Addr) {
+func fetchServiceLoadBalancerIPs(ingresses []core.LoadBalancerIngress) (results []netip.Addr, hostnames []string) {
 	for _, address := range ingresses {
 		if address.Hostname != "" {
-			log.Debugf("Looking up hostname %s", address.Hostname)
-			ips, err := net.LookupIP(address.Hostname)
-			if err != nil {
-				continue
-			}
-			for _, ip := range ips {
-				addr, err := netip.ParseAddr(ip.String())
-				if err != nil {
-					continue
-				}
-				results = append(results, addr)
-			}
+			hostnames = append(hostnames, address.Hostname)
 		} else if address.IP != "" {
 			addr, err := netip.ParseAddr(address.IP)
 			if err != nil {
@@ -868,21 +1381,10 @@ func fetchServiceLoadBalancerIPs(ingresses []core.LoadBalancerIngress) (results
 	return
 }
 
-func fetchIngressLoadBalancerIPs(ingresses []networking.IngressLoadBalancerIngress) (results []netip.Addr) {
+func fetchIngressLoadBalancerIPs(ingresses []networking.IngressLoadBalancerIngress) (results []netip.Addr, hostnames []string) {
 	for _, address := range ingresses {
 		if address.Hostname != "" {
-			log.Debugf("Looking up hostname %s", address.Hostname)
-			ips, err := net.LookupIP(address.Hostname)
-			if err != nil {
-				continue
-			}
-			for _, ip := range ips {
-				addr, err := netip.ParseAddr(ip.String())
-				if err != nil {
-					continue
-				}
-				results = append(results, addr)
-			}
+			hostnames = append(hostnames, address.Hostname)
 		} else if address.IP != "" {
 			addr, err := netip.ParseAddr(address.IP)
 			if err != nil {
@@ -894,6 +1396,25 @@ func fetchIngressLoadBalancerIPs(ingresses []networking.IngressLoadBalancerIngre
 	return
 }
 
+// lookupHostname resolves a load balancer hostname with the system resolver.
+func lookupHostname(hostname string) (results []netip.Addr) {
+	log.Debugf("Looking up hostname %s", hostname)
+	ips, err := net.LookupIP(hostname)
+	if err != nil {
+		hostnameLookupFailures.Inc()
+		log.Debugf("Failed to look up hostname %s: %v", hostname, err)
+		return
+	}
+	for _, ip := range ips {
+		addr, err := netip.ParseAddr(ip.String())
+		if err != nil {
+			continue
+		}
+		results = append(results, addr)
+	}
+	return
+}
+
 // the below is borrowed from k/k's GitHub repo
 const (
 	dns1123ValueFmt     string = "[a-z0-9]([-a-z0-9]*[a-z0-9])?"
@@ -967,8 +1488,8 @@ func fetchNodeIPsByType(addresses []core.NodeAddress, addrType core.NodeAddressT
 	return
 }
 
-func lookupNodeIndex(ctrl cache.SharedIndexInformer, addrType core.NodeAddressType) func([]string) (results []netip.Addr, raws []string) {
-	return func(indexKeys []string) (result []netip.Addr, raw []string) {
+func lookupNodeIndex(ctrl cache.SharedIndexInformer, addrType core.NodeAddressType) func([]string) lookupResult {
+	return func(indexKeys []string) (result lookupResult) {
 		var objs []interface{}
 		for _, key := range indexKeys {
 			obj, _ := ctrl.GetIndexer().ByIndex(nodeHostnameIndex, strings.ToLower(key))
@@ -977,7 +1498,8 @@ func lookupNodeIndex(ctrl cache.SharedIndexInformer, addrType core.NodeAddressTy
 		log.Debugf("Found %d matching Node objects", len(objs))
 		for _, obj := range objs {
 			node, _ := obj.(*core.Node)
-			result = append(result, fetchNodeIPsByType(node.Status.Addresses, addrType)...)
+			result.observe(node)
+			result.addrs = append(result.addrs, fetchNodeIPsByType(node.Status.Addresses, addrType)...)
 		}
 		return
 	}
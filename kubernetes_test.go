@@ -2,15 +2,22 @@ package gateway
 
 import (
 	"context"
+	"errors"
+	"maps"
+	"net/netip"
+	"slices"
 	"strings"
 	"testing"
 
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
 	"github.com/coredns/coredns/plugin/test"
 	"github.com/miekg/dns"
 	core "k8s.io/api/core/v1"
 	discovery "k8s.io/api/discovery/v1"
 	networking "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/tools/cache"
@@ -41,7 +48,7 @@ func TestController(t *testing.T) {
 		if !isFound(index, found) {
 			t.Errorf("Ingress key %s not found in index: %v", index, found)
 		}
-		ips := fetchIngressLoadBalancerIPs(testObj.Status.LoadBalancer.Ingress)
+		ips, _ := fetchIngressLoadBalancerIPs(testObj.Status.LoadBalancer.Ingress)
 		if len(ips) != 1 {
 			t.Errorf("Unexpected number of IPs found %d", len(ips))
 		}
@@ -61,7 +68,7 @@ func TestController(t *testing.T) {
 				t.Errorf("Service key %s not found in index: %v", idx, found)
 			}
 		}
-		ips := fetchServiceLoadBalancerIPs(testObj.Status.LoadBalancer.Ingress)
+		ips, _ := fetchServiceLoadBalancerIPs(testObj.Status.LoadBalancer.Ingress)
 		if len(ips) != 1 {
 			t.Errorf("Unexpected number of IPs found %d", len(ips))
 		}
@@ -517,7 +524,7 @@ func TestLookupNodeIndexNoHostname(t *testing.T) {
 	fakeInformer := &fakeSharedIndexInformer{indexer: fakeIndexer}
 
 	lookup := lookupNodeIndex(fakeInformer, core.NodeExternalIP)
-	results, _ := lookup([]string{"node-no-hostname"})
+	results := lookup([]string{"node-no-hostname"}).addrs
 	if len(results) != 0 {
 		t.Errorf("expected no results for node without NodeHostName, got: %v", results)
 	}
@@ -751,20 +758,20 @@ func TestMultiSelectorServiceLookup(t *testing.T) {
 	})
 	endpointSliceInformer := &fakeSharedIndexInformer{indexer: endpointSliceIndexer}
 
-	lookup := lookupServiceIndex(controllers, endpointSliceInformer)
+	lookup := lookupServiceIndex(controllers, informers{endpointSliceInformer})
 
 	t.Run("union of disjoint selectors returns both services", func(t *testing.T) {
-		results1, _ := lookup([]string{"service1.example.com"})
+		results1 := lookup([]string{"service1.example.com"}).addrs
 		if len(results1) != 1 || results1[0].String() != "10.0.0.1" {
 			t.Errorf("expected [10.0.0.1], got %v", results1)
 		}
 
-		results2, _ := lookup([]string{"service2.example.com"})
+		results2 := lookup([]string{"service2.example.com"}).addrs
 		if len(results2) != 1 || results2[0].String() != "10.0.0.2" {
 			t.Errorf("expected [10.0.0.2], got %v", results2)
 		}
 
-		results3, _ := lookup([]string{"service3.example.com"})
+		results3 := lookup([]string{"service3.example.com"}).addrs
 		if len(results3) != 0 {
 			t.Errorf("expected no results for service3, got %v", results3)
 		}
@@ -774,7 +781,7 @@ func TestMultiSelectorServiceLookup(t *testing.T) {
 		if err := indexer2.Add(service1); err != nil {
 			t.Fatalf("failed to add duplicate: %v", err)
 		}
-		results, _ := lookup([]string{"service1.example.com"})
+		results := lookup([]string{"service1.example.com"}).addrs
 		if len(results) != 1 {
 			t.Errorf("expected 1 result after dedup, got %d: %v", len(results), results)
 		}
@@ -900,7 +907,7 @@ func TestEndpointSliceAddresses(t *testing.T) {
 	informer := &fakeSharedIndexInformer{indexer: indexer}
 
 	service := &core.Service{ObjectMeta: metav1.ObjectMeta{Name: "backend", Namespace: "default"}}
-	addrs := endpointSliceAddresses(informer, service)
+	addrs := endpointSliceAddresses(informers{informer}, service)
 
 	got := make(map[string]bool, len(addrs))
 	for _, a := range addrs {
@@ -961,11 +968,11 @@ func TestLookupServiceIndexResolvesEndpoints(t *testing.T) {
 
 	lookup := lookupServiceIndex(
 		[]cache.SharedIndexInformer{&fakeSharedIndexInformer{indexer: serviceIndexer}},
-		&fakeSharedIndexInformer{indexer: endpointSliceIndexer},
+		informers{&fakeSharedIndexInformer{indexer: endpointSliceIndexer}},
 	)
 
 	// Default hostname for an opted-in service is name.namespace.
-	results, _ := lookup([]string{"backend.default"})
+	results := lookup([]string{"backend.default"}).addrs
 
 	got := make(map[string]bool, len(results))
 	for _, a := range results {
@@ -975,3 +982,325 @@ func TestLookupServiceIndexResolvesEndpoints(t *testing.T) {
 		t.Errorf("expected endpoint IPs [10.2.0.1 10.2.0.2], got %v", results)
 	}
 }
+
+// TestFetchLoadBalancerHostnames verifies that hostname load balancer entries
+// are returned as hostnames instead of being resolved in the lookup path.
+func TestFetchLoadBalancerHostnames(t *testing.T) {
+	addrs, hostnames := fetchServiceLoadBalancerIPs([]core.LoadBalancerIngress{
+		{Hostname: "abc.elb.amazonaws.com"},
+		{IP: "192.0.2.1"},
+	})
+	if len(addrs) != 1 || addrs[0].String() != "192.0.2.1" {
+		t.Errorf("expected service address [192.0.2.1], got %v", addrs)
+	}
+	if len(hostnames) != 1 || hostnames[0] != "abc.elb.amazonaws.com" {
+		t.Errorf("expected service hostname [abc.elb.amazonaws.com], got %v", hostnames)
+	}
+
+	addrs, hostnames = fetchIngressLoadBalancerIPs([]networking.IngressLoadBalancerIngress{
+		{Hostname: "def.elb.amazonaws.com"},
+	})
+	if len(addrs) != 0 {
+		t.Errorf("expected no ingress addresses, got %v", addrs)
+	}
+	if len(hostnames) != 1 || hostnames[0] != "def.elb.amazonaws.com" {
+		t.Errorf("expected ingress hostname [def.elb.amazonaws.com], got %v", hostnames)
+	}
+}
+
+func TestServicePorts(t *testing.T) {
+	ports := fetchServicePorts([]core.ServicePort{
+		{Name: "http", Port: 80, Protocol: core.ProtocolTCP},
+		{Name: "dns", Port: 53, Protocol: core.ProtocolUDP},
+		{Port: 8080}, // unnamed, skipped
+	})
+	want := []servicePort{{"http", "TCP", 80}, {"dns", "UDP", 53}}
+	if !slices.Equal(ports, want) {
+		t.Errorf("expected service ports %v, got %v", want, ports)
+	}
+
+	name := "http"
+	port := int32(8080)
+	es := &discovery.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backend-1",
+			Namespace: "default",
+			Labels:    map[string]string{discovery.LabelServiceName: "backend"},
+		},
+		Ports: []discovery.EndpointPort{{Name: &name, Port: &port}},
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		endpointSliceServiceIndex: endpointSliceServiceIndexFunc,
+	})
+	if err := indexer.Add(es); err != nil {
+		t.Fatalf("failed to add endpoint slice: %v", err)
+	}
+
+	service := &core.Service{ObjectMeta: metav1.ObjectMeta{Name: "backend", Namespace: "default"}}
+	ports = endpointSlicePorts(informers{&fakeSharedIndexInformer{indexer: indexer}}, service)
+	want = []servicePort{{"http", "TCP", 8080}}
+	if !slices.Equal(ports, want) {
+		t.Errorf("expected endpoint ports %v, got %v", want, ports)
+	}
+}
+
+func TestKubeControllerRun(t *testing.T) {
+	client := fake.NewClientset()
+	ctrl := &KubeController{client: client}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctrl.addController("Service", cache.NewSharedIndexInformer(
+		// the fake client doesn't support streaming lists
+		cache.ToListWatcherWithWatchListSemantics(&cache.ListWatch{
+			ListFunc:  serviceLister(ctx, client, core.NamespaceAll, ""),
+			WatchFunc: serviceWatcher(ctx, client, core.NamespaceAll, ""),
+		}, client),
+		&core.Service{},
+		defaultResyncPeriod,
+		cache.Indexers{serviceHostnameIndex: serviceHostnameIndexFunc},
+	))
+
+	if unsynced := ctrl.unsynced(); !slices.Equal(unsynced, []string{"Service"}) {
+		t.Errorf("expected Service to be unsynced before running, got %v", unsynced)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ctrl.run(ctx)
+		close(done)
+	}()
+	<-done
+
+	if !ctrl.HasSynced() {
+		t.Errorf("expected the controller to be synced")
+	}
+	if unsynced := ctrl.unsynced(); len(unsynced) != 0 {
+		t.Errorf("expected no unsynced resources, got %v", unsynced)
+	}
+	cancel()
+}
+
+func TestKubeControllerRunStopped(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ctrl := &KubeController{}
+	ctrl.addController("Ingress", cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(metav1.ListOptions) (runtime.Object, error) {
+				return nil, errors.New("forbidden")
+			},
+			WatchFunc: func(metav1.ListOptions) (watch.Interface, error) {
+				return nil, errors.New("forbidden")
+			},
+		},
+		&networking.Ingress{},
+		defaultResyncPeriod,
+		cache.Indexers{},
+	))
+
+	cancel()
+	ctrl.run(ctx)
+
+	if ctrl.HasSynced() {
+		t.Errorf("expected the controller not to be synced after being stopped before the sync")
+	}
+}
+
+func TestAnnotationTTL(t *testing.T) {
+	tests := []struct {
+		annotations map[string]string
+		expected    uint32
+	}{
+		{nil, 0},
+		{map[string]string{ttlAnnotationKey: "30"}, 30},
+		{map[string]string{externalDnsTTLAnnotationKey: "300"}, 300},
+		{map[string]string{ttlAnnotationKey: "5m"}, 300},
+		{map[string]string{ttlAnnotationKey: "10", externalDnsTTLAnnotationKey: "300"}, 10},
+		{map[string]string{ttlAnnotationKey: "-1"}, 0},
+		{map[string]string{ttlAnnotationKey: "500ms"}, 0},
+		{map[string]string{ttlAnnotationKey: "soon"}, 0},
+	}
+
+	for i, tc := range tests {
+		if got := annotationTTL(metav1.ObjectMeta{Name: "obj", Namespace: "ns1", Annotations: tc.annotations}); got != tc.expected {
+			t.Errorf("Test %d: expected TTL %d for %v, got %d", i, tc.expected, tc.annotations, got)
+		}
+	}
+}
+
+func TestLookupTTL(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{serviceHostnameIndex: serviceHostnameIndexFunc})
+	for name, ttl := range map[string]string{"svc1": "30", "svc2": "10"} {
+		svc := &core.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       "ns1",
+				ResourceVersion: ttl + "00",
+				Annotations:     map[string]string{hostnameAnnotationKey: "bluegreen.example.com", ttlAnnotationKey: ttl},
+			},
+			Spec: core.ServiceSpec{Type: core.ServiceTypeLoadBalancer},
+			Status: core.ServiceStatus{
+				LoadBalancer: core.LoadBalancerStatus{Ingress: []core.LoadBalancerIngress{{IP: "192.0.2.1"}}},
+			},
+		}
+		if err := indexer.Add(svc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lookup := lookupServiceIndex(informers{&fakeSharedIndexInformer{indexer: indexer}}, nil)
+	result := lookup([]string{"bluegreen.example.com"})
+	if result.ttl != 10 {
+		t.Errorf("expected the lowest TTL 10, got %d", result.ttl)
+	}
+	if result.revision != 3000 {
+		t.Errorf("expected the highest resourceVersion 3000, got %d", result.revision)
+	}
+
+	gwResult := lookupResult{addrs: []netip.Addr{netip.MustParseAddr("192.0.2.1")}, ttl: 300}
+	if result := withRouteTTL(gwResult, metav1.ObjectMeta{}); result.ttl != 300 {
+		t.Errorf("expected the Gateway TTL 300 without route annotation, got %d", result.ttl)
+	}
+	route := metav1.ObjectMeta{ResourceVersion: "4711", Annotations: map[string]string{ttlAnnotationKey: "600"}}
+	if result := withRouteTTL(gwResult, route); result.ttl != 600 || result.revision != 4711 {
+		t.Errorf("expected the route TTL 600 to override the Gateway TTL and its resourceVersion 4711 to be recorded, got %d and %d", result.ttl, result.revision)
+	}
+}
+
+func TestPluginExternalNameService(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{serviceHostnameIndex: serviceHostnameIndexFunc})
+	for _, svc := range []*core.Service{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "saas", Namespace: "ns1", Annotations: map[string]string{hostnameAnnotationKey: "saas.example.com"}},
+			Spec:       core.ServiceSpec{Type: core.ServiceTypeExternalName, ExternalName: "tenant.vendor.org."},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "portal", Namespace: "ns1", Annotations: map[string]string{hostnameAnnotationKey: "portal.example.com"}},
+			Spec:       core.ServiceSpec{Type: core.ServiceTypeExternalName, ExternalName: "web.example.com"},
+		},
+		{
+			// not published without a hostname annotation
+			ObjectMeta: metav1.ObjectMeta{Name: "plain", Namespace: "ns1"},
+			Spec:       core.ServiceSpec{Type: core.ServiceTypeExternalName, ExternalName: "tenant.vendor.org"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "ns1", Annotations: map[string]string{hostnameAnnotationKey: "web.example.com"}},
+			Spec:       core.ServiceSpec{Type: core.ServiceTypeLoadBalancer},
+			Status: core.ServiceStatus{
+				LoadBalancer: core.LoadBalancerStatus{Ingress: []core.LoadBalancerIngress{{IP: "192.0.2.20"}}},
+			},
+		},
+	} {
+		if err := indexer.Add(svc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gw := newGateway()
+	gw.Zones = []string{"example.com."}
+	gw.Next = test.NextHandler(dns.RcodeSuccess, nil)
+	gw.ExternalAddrFunc = gw.SelfAddress
+	gw.Controller = &KubeController{hasSynced: true}
+	gw.updateResources([]string{"Service"})
+	gw.lookupResource("Service").lookup = lookupServiceIndex(informers{&fakeSharedIndexInformer{indexer: indexer}}, nil)
+
+	tests := []test.Case{
+		// CNAME to an external name | Test 0
+		{
+			Qname: "saas.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.CNAME("saas.example.com.  60  IN  CNAME  tenant.vendor.org."),
+			},
+		},
+		// CNAME to an in-zone name is chased | Test 1
+		{
+			Qname: "portal.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.CNAME("portal.example.com.  60  IN  CNAME  web.example.com."),
+				test.A("web.example.com.  60  IN  A  192.0.2.20"),
+			},
+		},
+		// ExternalName Service without hostname annotation | Test 2
+		{
+			Qname: "plain.ns1.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+			Ns: []dns.RR{
+				test.SOA("example.com.  60  IN  SOA dns1.kube-system.example.com. hostmaster.example.com. 1499347823 7200 1800 86400 5"),
+			},
+		},
+	}
+
+	ctx := context.TODO()
+	for i, tc := range tests {
+		r := tc.Msg()
+		w := dnstest.NewRecorder(&test.ResponseWriter{})
+
+		if _, err := gw.ServeDNS(ctx, w, r); err != nil {
+			t.Errorf("Test %d: unexpected error: %v", i, err)
+			continue
+		}
+		if err := test.SortAndCheck(w.Msg, tc); err != nil {
+			t.Errorf("Test %d failed with error: %v", i, err)
+		}
+	}
+}
+
+func TestSharedInformers(t *testing.T) {
+	ctx := context.TODO()
+	ctrl := &KubeController{client: fake.NewClientset(), namespaces: []string{"ns1", "ns2"}}
+
+	services := ctrl.sharedServices(ctx, cache.Indexers{serviceHostnameIndex: serviceHostnameIndexFunc})
+	if again := ctrl.sharedServices(ctx, cache.Indexers{"other": cache.MetaNamespaceIndexFunc}); !slices.Equal(services, again) {
+		t.Errorf("expected the Service informers to be shared")
+	}
+	ctrl.sharedEndpointSlices(ctx, cache.Indexers{endpointSliceServiceIndex: endpointSliceServiceIndexFunc})
+	ctrl.sharedEndpointSlices(ctx, cache.Indexers{serviceImportEndpointSliceIndex: serviceImportEndpointSliceIndexFunc})
+
+	// one informer per namespace, each registered once
+	if !slices.Equal(ctrl.resources, []string{"Service", "Service", "EndpointSlice", "EndpointSlice"}) {
+		t.Errorf("expected shared informers to be registered once, got %v", ctrl.resources)
+	}
+	for _, informer := range services {
+		indexers := informer.GetIndexer().GetIndexers()
+		if _, ok := indexers[serviceHostnameIndex]; !ok {
+			t.Errorf("expected the %s index to be added", serviceHostnameIndex)
+		}
+		if _, ok := indexers["other"]; !ok {
+			t.Errorf("expected the indexers of every user to be added")
+		}
+	}
+	for _, informer := range ctrl.endpointSlices {
+		if indexers := informer.GetIndexer().GetIndexers(); len(indexers) != 2 {
+			t.Errorf("expected 2 EndpointSlice indexers, got %v", slices.Collect(maps.Keys(indexers)))
+		}
+	}
+}
+
+func TestSelectedServiceIndexFunc(t *testing.T) {
+	newService := func(name string, labels map[string]string) *core.Service {
+		return &core.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns1", Labels: labels},
+			Spec:       core.ServiceSpec{Type: core.ServiceTypeLoadBalancer},
+		}
+	}
+
+	tests := []struct {
+		selectors []string
+		service   *core.Service
+		expected  []string
+	}{
+		{nil, newService("svc1", nil), []string{"svc1.ns1"}},
+		{[]string{"app=svc1"}, newService("svc1", map[string]string{"app": "svc1"}), []string{"svc1.ns1"}},
+		{[]string{"app=svc1"}, newService("svc2", map[string]string{"app": "svc2"}), []string{}},
+		{[]string{"app=svc1", "app=svc2"}, newService("svc2", map[string]string{"app": "svc2"}), []string{"svc2.ns1"}},
+		{[]string{"app!=svc2"}, newService("svc3", nil), []string{"svc3.ns1"}},
+	}
+
+	for i, tc := range tests {
+		got, err := selectedServiceIndexFunc(tc.selectors)(tc.service)
+		if err != nil {
+			t.Fatalf("Test %d: unexpected error: %v", i, err)
+		}
+		if !slices.Equal(got, tc.expected) {
+			t.Errorf("Test %d: expected index %v, got %v", i, tc.expected, got)
+		}
+	}
+}
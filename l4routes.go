@@ -0,0 +1,200 @@
+package gateway
+
+import (
+	"context"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	gatewayapi_v1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayapi_v1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+	gatewayClient "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned"
+)
+
+// TCPRoutes and UDPRoutes of the experimental channel have no hostnames of their
+// own, they are published under the hostnames annotated on the route instead.
+const (
+	tcpRouteHostnameIndex = "tcpRouteHostname"
+	udpRouteHostnameIndex = "udpRouteHostname"
+)
+
+func initializeTCPRouteController(ctx context.Context, ctrl *KubeController, parents parentInformers, originalGateway *Gateway) informers {
+	tcpRouteControllers := ctrl.forEachNamespace(func(ns string) cache.SharedIndexInformer {
+		return cache.NewSharedIndexInformer(
+			&cache.ListWatch{
+				ListFunc:  tcpRouteLister(ctx, ctrl.gwClient, ns),
+				WatchFunc: tcpRouteWatcher(ctx, ctrl.gwClient, ns),
+			},
+			&gatewayapi_v1alpha2.TCPRoute{},
+			defaultResyncPeriod,
+			cache.Indexers{
+				tcpRouteHostnameIndex: tcpRouteHostnameIndexFunc,
+				routeParentIndex:      routeParentIndexFunc,
+			},
+		)
+	})
+	originalGateway.lookupResource("TCPRoute").lookup = lookupTCPRouteIndex(
+		tcpRouteControllers,
+		parents,
+		originalGateway.resourceFilters.gatewayClasses,
+		originalGateway.resourceFilters.routeStatus,
+	)
+	originalGateway.lookupResource("TCPRoute").keys = listIndexKeys(tcpRouteHostnameIndex, tcpRouteControllers...)
+	return tcpRouteControllers
+}
+
+func initializeUDPRouteController(ctx context.Context, ctrl *KubeController, parents parentInformers, originalGateway *Gateway) informers {
+	udpRouteControllers := ctrl.forEachNamespace(func(ns string) cache.SharedIndexInformer {
+		return cache.NewSharedIndexInformer(
+			&cache.ListWatch{
+				ListFunc:  udpRouteLister(ctx, ctrl.gwClient, ns),
+				WatchFunc: udpRouteWatcher(ctx, ctrl.gwClient, ns),
+			},
+			&gatewayapi_v1alpha2.UDPRoute{},
+			defaultResyncPeriod,
+			cache.Indexers{
+				udpRouteHostnameIndex: udpRouteHostnameIndexFunc,
+				routeParentIndex:      routeParentIndexFunc,
+			},
+		)
+	})
+	originalGateway.lookupResource("UDPRoute").lookup = lookupUDPRouteIndex(
+		udpRouteControllers,
+		parents,
+		originalGateway.resourceFilters.gatewayClasses,
+		originalGateway.resourceFilters.routeStatus,
+	)
+	originalGateway.lookupResource("UDPRoute").keys = listIndexKeys(udpRouteHostnameIndex, udpRouteControllers...)
+	return udpRouteControllers
+}
+
+func tcpRouteLister(ctx context.Context, c gatewayClient.Interface, ns string) func(metav1.ListOptions) (runtime.Object, error) {
+	return func(opts metav1.ListOptions) (runtime.Object, error) {
+		return c.GatewayV1alpha2().TCPRoutes(ns).List(ctx, opts)
+	}
+}
+
+func tcpRouteWatcher(ctx context.Context, c gatewayClient.Interface, ns string) func(metav1.ListOptions) (watch.Interface, error) {
+	return func(opts metav1.ListOptions) (watch.Interface, error) {
+		return c.GatewayV1alpha2().TCPRoutes(ns).Watch(ctx, opts)
+	}
+}
+
+func udpRouteLister(ctx context.Context, c gatewayClient.Interface, ns string) func(metav1.ListOptions) (runtime.Object, error) {
+	return func(opts metav1.ListOptions) (runtime.Object, error) {
+		return c.GatewayV1alpha2().UDPRoutes(ns).List(ctx, opts)
+	}
+}
+
+func udpRouteWatcher(ctx context.Context, c gatewayClient.Interface, ns string) func(metav1.ListOptions) (watch.Interface, error) {
+	return func(opts metav1.ListOptions) (watch.Interface, error) {
+		return c.GatewayV1alpha2().UDPRoutes(ns).Watch(ctx, opts)
+	}
+}
+
+func tcpRouteHostnameIndexFunc(obj interface{}) ([]string, error) {
+	tcpRoute, ok := obj.(*gatewayapi_v1alpha2.TCPRoute)
+	if !ok {
+		return []string{}, nil
+	}
+
+	// Check if object should be ignored
+	if checkIgnoreLabel(tcpRoute.Labels) {
+		log.Debugf("Ignoring tcpRoute %s due to %s label", tcpRoute.Name, ignoreLabelKey)
+		return []string{}, nil
+	}
+
+	hostnames := annotatedHostnames(tcpRoute.ObjectMeta)
+	log.Debugf("Adding index %v for tcpRoute %s", hostnames, tcpRoute.Name)
+	return hostnames, nil
+}
+
+func udpRouteHostnameIndexFunc(obj interface{}) ([]string, error) {
+	udpRoute, ok := obj.(*gatewayapi_v1alpha2.UDPRoute)
+	if !ok {
+		return []string{}, nil
+	}
+
+	// Check if object should be ignored
+	if checkIgnoreLabel(udpRoute.Labels) {
+		log.Debugf("Ignoring udpRoute %s due to %s label", udpRoute.Name, ignoreLabelKey)
+		return []string{}, nil
+	}
+
+	hostnames := annotatedHostnames(udpRoute.ObjectMeta)
+	log.Debugf("Adding index %v for udpRoute %s", hostnames, udpRoute.Name)
+	return hostnames, nil
+}
+
+// annotatedHostnames returns the valid hostnames of the hostname annotation of an
+// object, the same annotations Services can be published under.
+func annotatedHostnames(obj metav1.ObjectMeta) (hostnames []string) {
+	for _, key := range []string{hostnameAnnotationKey, externalDnsHostnameAnnotationKey} {
+		annotation, exists := obj.Annotations[key]
+		if !exists {
+			continue
+		}
+		for _, hostname := range splitHostnameAnnotation(strings.ToLower(annotation)) {
+			if checkDomainValid(hostname) {
+				hostnames = append(hostnames, hostname)
+			}
+		}
+		break
+	}
+	return hostnames
+}
+
+func lookupTCPRouteIndex(tcp informers, parents parentInformers, gwclasses []string, checkStatus bool) func([]string) lookupResult {
+	return func(indexKeys []string) (result lookupResult) {
+		var objs []interface{}
+		for _, key := range indexKeys {
+			objs = append(objs, tcp.byIndex(tcpRouteHostnameIndex, strings.ToLower(key))...)
+		}
+		log.Debugf("Found %d matching tcpRoute objects", len(objs))
+
+		for _, obj := range objs {
+			tcpRoute, _ := obj.(*gatewayapi_v1alpha2.TCPRoute)
+			route := attachedRoute{
+				namespace:  tcpRoute.Namespace,
+				parentRefs: tcpRoute.Spec.ParentRefs,
+				hostnames:  annotatedRouteHostnames(tcpRoute.ObjectMeta),
+				status:     statusToCheck(checkStatus, &tcpRoute.Status.RouteStatus),
+			}
+			result = result.merge(withRouteTTL(lookupGateways(parents, route, indexKeys, gwclasses, tcpRouteService), tcpRoute.ObjectMeta))
+		}
+		return
+	}
+}
+
+func lookupUDPRouteIndex(udp informers, parents parentInformers, gwclasses []string, checkStatus bool) func([]string) lookupResult {
+	return func(indexKeys []string) (result lookupResult) {
+		var objs []interface{}
+		for _, key := range indexKeys {
+			objs = append(objs, udp.byIndex(udpRouteHostnameIndex, strings.ToLower(key))...)
+		}
+		log.Debugf("Found %d matching udpRoute objects", len(objs))
+
+		for _, obj := range objs {
+			udpRoute, _ := obj.(*gatewayapi_v1alpha2.UDPRoute)
+			route := attachedRoute{
+				namespace:  udpRoute.Namespace,
+				parentRefs: udpRoute.Spec.ParentRefs,
+				hostnames:  annotatedRouteHostnames(udpRoute.ObjectMeta),
+				status:     statusToCheck(checkStatus, &udpRoute.Status.RouteStatus),
+			}
+			result = result.merge(withRouteTTL(lookupGateways(parents, route, indexKeys, gwclasses, udpRouteService), udpRoute.ObjectMeta))
+		}
+		return
+	}
+}
+
+// annotatedRouteHostnames returns the annotated hostnames of a route as the
+// hostnames lookupGateways matches the listeners against
+func annotatedRouteHostnames(obj metav1.ObjectMeta) (hostnames []gatewayapi_v1.Hostname) {
+	for _, hostname := range annotatedHostnames(obj) {
+		hostnames = append(hostnames, gatewayapi_v1.Hostname(hostname))
+	}
+	return hostnames
+}
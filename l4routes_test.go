@@ -0,0 +1,139 @@
+package gateway
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	gatewayapi_v1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayapi_v1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+)
+
+func TestL4RouteHostnameIndexFunc(t *testing.T) {
+	tests := []struct {
+		obj      interface{}
+		expected []string
+	}{
+		{&gatewayapi_v1alpha2.TCPRoute{ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{hostnameAnnotationKey: "DB.example.com, db.other.com"},
+		}}, []string{"db.example.com", "db.other.com"}},
+		{&gatewayapi_v1alpha2.TCPRoute{ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{externalDnsHostnameAnnotationKey: "db.example.com"},
+		}}, []string{"db.example.com"}},
+		// routes without annotation have no hostname
+		{&gatewayapi_v1alpha2.TCPRoute{}, nil},
+		{&gatewayapi_v1alpha2.TCPRoute{ObjectMeta: metav1.ObjectMeta{
+			Labels:      map[string]string{ignoreLabelKey: "true"},
+			Annotations: map[string]string{hostnameAnnotationKey: "db.example.com"},
+		}}, []string{}},
+	}
+
+	for i, tc := range tests {
+		got, _ := tcpRouteHostnameIndexFunc(tc.obj)
+		if !slices.Equal(got, tc.expected) {
+			t.Errorf("Test %d: expected index %v, got %v", i, tc.expected, got)
+		}
+	}
+
+	got, _ := udpRouteHostnameIndexFunc(&gatewayapi_v1alpha2.UDPRoute{ObjectMeta: metav1.ObjectMeta{
+		Annotations: map[string]string{hostnameAnnotationKey: "syslog.example.com"},
+	}})
+	if !slices.Equal(got, []string{"syslog.example.com"}) {
+		t.Errorf("expected UDPRoute index [syslog.example.com], got %v", got)
+	}
+}
+
+func TestL4RoutePlugin(t *testing.T) {
+	gatewayIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{gatewayUniqueIndex: gatewayIndexFunc})
+	tcpIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{tcpRouteHostnameIndex: tcpRouteHostnameIndexFunc})
+	udpIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{udpRouteHostnameIndex: udpRouteHostnameIndexFunc})
+
+	ipAddressType := gatewayapi_v1.IPAddressType
+	listenerHostname := gatewayapi_v1.Hostname("ignored.example.com")
+	gateway := &gatewayapi_v1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "gw-1", Namespace: "ns1"},
+		Spec: gatewayapi_v1.GatewaySpec{
+			Listeners: []gatewayapi_v1.Listener{
+				{Name: "postgres", Protocol: gatewayapi_v1.TCPProtocolType, Port: 5432, Hostname: &listenerHostname},
+				{Name: "syslog", Protocol: gatewayapi_v1.UDPProtocolType, Port: 514},
+				{Name: "http", Protocol: gatewayapi_v1.HTTPProtocolType, Port: 80},
+			},
+		},
+		Status: gatewayapi_v1.GatewayStatus{
+			Addresses: []gatewayapi_v1.GatewayStatusAddress{{Type: &ipAddressType, Value: "192.0.2.10"}},
+		},
+	}
+	parentRefs := []gatewayapi_v1.ParentReference{{Name: "gw-1"}}
+	tcpRoute := &gatewayapi_v1alpha2.TCPRoute{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "db",
+			Namespace:   "ns1",
+			Annotations: map[string]string{hostnameAnnotationKey: "db.example.com"},
+		},
+		Spec: gatewayapi_v1alpha2.TCPRouteSpec{CommonRouteSpec: gatewayapi_v1.CommonRouteSpec{ParentRefs: parentRefs}},
+	}
+	udpRoute := &gatewayapi_v1alpha2.UDPRoute{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "logs",
+			Namespace:   "ns1",
+			Annotations: map[string]string{hostnameAnnotationKey: "logs.example.com"},
+		},
+		Spec: gatewayapi_v1alpha2.UDPRouteSpec{CommonRouteSpec: gatewayapi_v1.CommonRouteSpec{ParentRefs: parentRefs}},
+	}
+	if err := gatewayIndexer.Add(gateway); err != nil {
+		t.Fatal(err)
+	}
+	if err := tcpIndexer.Add(tcpRoute); err != nil {
+		t.Fatal(err)
+	}
+	if err := udpIndexer.Add(udpRoute); err != nil {
+		t.Fatal(err)
+	}
+
+	gw := newGateway()
+	gw.Zones = []string{"example.com."}
+	gw.Next = test.NextHandler(dns.RcodeSuccess, nil)
+	gw.ExternalAddrFunc = gw.SelfAddress
+	gw.Controller = &KubeController{hasSynced: true}
+	gw.updateResources([]string{"TCPRoute", "UDPRoute"})
+	parents := parentInformers{gateways: informers{&fakeSharedIndexInformer{indexer: gatewayIndexer}}}
+	gw.lookupResource("TCPRoute").lookup = lookupTCPRouteIndex(informers{&fakeSharedIndexInformer{indexer: tcpIndexer}}, parents, nil, false)
+	gw.lookupResource("UDPRoute").lookup = lookupUDPRouteIndex(informers{&fakeSharedIndexInformer{indexer: udpIndexer}}, parents, nil, false)
+
+	tests := []test.Case{
+		{
+			Qname: "db.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{test.A("db.example.com.	60	IN	A	192.0.2.10")},
+		},
+		{
+			Qname: "_postgres._tcp.db.example.com.", Qtype: dns.TypeSRV, Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{test.SRV("_postgres._tcp.db.example.com.	60	IN	SRV	0 100 5432 db.example.com.")},
+			Extra:  []dns.RR{test.A("db.example.com.	60	IN	A	192.0.2.10")},
+		},
+		{
+			Qname: "_syslog._udp.logs.example.com.", Qtype: dns.TypeSRV, Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{test.SRV("_syslog._udp.logs.example.com.	60	IN	SRV	0 100 514 logs.example.com.")},
+			Extra:  []dns.RR{test.A("logs.example.com.	60	IN	A	192.0.2.10")},
+		},
+		// listeners of other protocols aren't published for the route
+		{
+			Qname: "_syslog._udp.db.example.com.", Qtype: dns.TypeSRV, Rcode: dns.RcodeNameError,
+			Ns: []dns.RR{test.SOA("example.com.	60	IN	SOA	dns1.kube-system.example.com. hostmaster.example.com. 1499347823 7200 1800 86400 5")},
+		},
+	}
+
+	for i, tc := range tests {
+		w := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := gw.ServeDNS(context.TODO(), w, tc.Msg()); err != nil {
+			t.Fatalf("Test %d: unexpected error: %v", i, err)
+		}
+		if err := test.SortAndCheck(w.Msg, tc); err != nil {
+			t.Errorf("Test %d failed with error: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package gateway
+
+import (
+	"slices"
+	"strings"
+
+	gatewayapi_v1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// routeService describes a kind of route, which listeners accept it and what the
+// HTTPS records of its hostnames advertise.
+type routeService struct {
+	kind gatewayapi_v1.Kind
+	// protocols are the listener protocols accepting the route if the listener
+	// doesn't restrict the kinds of routes itself
+	protocols []gatewayapi_v1.ProtocolType
+	// alpn is advertised for HTTPS listeners
+	alpn []string
+	// srvProtocol publishes the listeners as SRV records of the given protocol
+	srvProtocol string
+}
+
+var (
+	httpRouteService = routeService{
+		kind:      "HTTPRoute",
+		protocols: []gatewayapi_v1.ProtocolType{gatewayapi_v1.HTTPProtocolType, gatewayapi_v1.HTTPSProtocolType},
+		alpn:      []string{"h2", "http/1.1"},
+	}
+	grpcRouteService = routeService{
+		kind:      "GRPCRoute",
+		protocols: []gatewayapi_v1.ProtocolType{gatewayapi_v1.HTTPProtocolType, gatewayapi_v1.HTTPSProtocolType},
+		alpn:      []string{"h2"},
+	}
+	tlsRouteService = routeService{
+		kind:      "TLSRoute",
+		protocols: []gatewayapi_v1.ProtocolType{gatewayapi_v1.TLSProtocolType},
+	}
+	tcpRouteService = routeService{
+		kind:        "TCPRoute",
+		protocols:   []gatewayapi_v1.ProtocolType{gatewayapi_v1.TCPProtocolType},
+		srvProtocol: "tcp",
+	}
+	udpRouteService = routeService{
+		kind:        "UDPRoute",
+		protocols:   []gatewayapi_v1.ProtocolType{gatewayapi_v1.UDPProtocolType},
+		srvProtocol: "udp",
+	}
+)
+
+// isGatewayRef reports whether a parent reference refers to a Gateway, which is
+// the default for both the group and the kind.
+func isGatewayRef(ref gatewayapi_v1.ParentReference) bool {
+	if ref.Group != nil && *ref.Group != gatewayapi_v1.GroupName {
+		return false
+	}
+	return ref.Kind == nil || *ref.Kind == "Gateway"
+}
+
+// matchingListeners returns the listeners of the parent that the parent reference
+// of a route in routeNamespace attaches to and that accept the route.
+func matchingListeners(parent routeParent, ref gatewayapi_v1.ParentReference, routeNamespace string, service routeService) (listeners []gatewayapi_v1.Listener) {
+	for _, listener := range parent.listeners {
+		if ref.SectionName != nil && *ref.SectionName != listener.Name {
+			continue
+		}
+		if ref.Port != nil && *ref.Port != listener.Port {
+			continue
+		}
+		if !listenerAllowsKind(listener, service) || !listenerAllowsNamespace(listener, parent.object.Namespace, routeNamespace) {
+			continue
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners
+}
+
+func listenerAllowsKind(listener gatewayapi_v1.Listener, service routeService) bool {
+	if listener.AllowedRoutes == nil || len(listener.AllowedRoutes.Kinds) == 0 {
+		return slices.Contains(service.protocols, listener.Protocol)
+	}
+	return slices.ContainsFunc(listener.AllowedRoutes.Kinds, func(kind gatewayapi_v1.RouteGroupKind) bool {
+		return kind.Kind == service.kind && (kind.Group == nil || *kind.Group == gatewayapi_v1.GroupName)
+	})
+}
+
+// listenerAllowsNamespace reports whether the listener accepts routes from the
+// namespace of the route. Namespace selectors can't be evaluated without watching
+// namespaces, so they are taken to match.
+func listenerAllowsNamespace(listener gatewayapi_v1.Listener, gatewayNamespace, routeNamespace string) bool {
+	from := gatewayapi_v1.NamespacesFromSame
+	if listener.AllowedRoutes != nil && listener.AllowedRoutes.Namespaces != nil && listener.AllowedRoutes.Namespaces.From != nil {
+		from = *listener.AllowedRoutes.Namespaces.From
+	}
+	switch from {
+	case gatewayapi_v1.NamespacesFromAll, gatewayapi_v1.NamespacesFromSelector:
+		return true
+	case gatewayapi_v1.NamespacesFromSame:
+		return gatewayNamespace == routeNamespace
+	default:
+		return false
+	}
+}
+
+// effectiveHostnames returns the hostnames a route with the given hostnames is
+// served under by the listener. A route without hostnames inherits the hostname of
+// the listener, otherwise its hostnames are intersected with the listener's.
+func effectiveHostnames(listener gatewayapi_v1.Listener, routeHostnames []gatewayapi_v1.Hostname) (hostnames []string) {
+	// the hostname of TCP and UDP listeners is ignored, as there is no way to match it
+	if listener.Hostname == nil || *listener.Hostname == "" ||
+		listener.Protocol == gatewayapi_v1.TCPProtocolType || listener.Protocol == gatewayapi_v1.UDPProtocolType {
+		for _, hostname := range routeHostnames {
+			hostnames = append(hostnames, string(hostname))
+		}
+		return hostnames
+	}
+	if len(routeHostnames) == 0 {
+		return []string{string(*listener.Hostname)}
+	}
+	for _, hostname := range routeHostnames {
+		if intersection, ok := intersectHostnames(string(*listener.Hostname), string(hostname)); ok {
+			hostnames = append(hostnames, intersection)
+		}
+	}
+	return hostnames
+}
+
+// intersectHostnames returns the more specific of two hostnames if one of them
+// matches the other, e.g. foo.example.com for *.example.com and foo.example.com.
+func intersectHostnames(a, b string) (string, bool) {
+	switch {
+	case strings.EqualFold(a, b), wildcardMatches(a, b):
+		return b, true
+	case wildcardMatches(b, a):
+		return a, true
+	}
+	return "", false
+}
+
+// wildcardMatches reports whether a wildcard hostname matches a hostname with at
+// least one more label, which may itself be a wildcard.
+func wildcardMatches(wildcard, hostname string) bool {
+	suffix, ok := strings.CutPrefix(wildcard, "*")
+	if !ok || !strings.HasPrefix(suffix, ".") {
+		return false
+	}
+	return len(hostname) > len(suffix) && strings.HasSuffix(strings.ToLower(hostname), strings.ToLower(suffix))
+}
+
+// servingListeners returns the listeners that serve the route under one of the
+// index keys of a query.
+func servingListeners(listeners []gatewayapi_v1.Listener, routeHostnames []gatewayapi_v1.Hostname, indexKeys []string) (serving []gatewayapi_v1.Listener) {
+	for _, listener := range listeners {
+		for _, hostname := range effectiveHostnames(listener, routeHostnames) {
+			if slices.ContainsFunc(indexKeys, func(key string) bool { return strings.EqualFold(key, hostname) }) {
+				serving = append(serving, listener)
+				break
+			}
+		}
+	}
+	return serving
+}
+
+// listenerPorts returns the ports of the listeners published as SRV records, named
+// after the listener, e.g. _syslog._udp.<hostname>
+func listenerPorts(listeners []gatewayapi_v1.Listener, service routeService) (ports []servicePort) {
+	if service.srvProtocol == "" {
+		return nil
+	}
+	for _, listener := range listeners {
+		ports = append(ports, servicePort{name: string(listener.Name), protocol: service.srvProtocol, port: uint16(listener.Port)})
+	}
+	return ports
+}
@@ -0,0 +1,252 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	gatewayapi_v1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayClient "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned"
+)
+
+// ListenerSets add listeners to a Gateway without editing it. Routes attached to a
+// ListenerSet are served by the addresses of its parent Gateway.
+const (
+	listenerSetUniqueIndex           = "listenerSetIndex"
+	listenerSetListenerHostnameIndex = "listenerSetListenerHostname"
+)
+
+// parentInformers holds the informers of the resources routes attach to
+type parentInformers struct {
+	gateways     informers
+	listenerSets informers
+}
+
+// routeParent is a Gateway a route attaches to, either directly or through one of
+// the Gateway's ListenerSets.
+type routeParent struct {
+	gateway *gatewayapi_v1.Gateway
+	// kind, metadata and listeners of the Gateway or the ListenerSet
+	kind      gatewayapi_v1.Kind
+	object    metav1.ObjectMeta
+	listeners []gatewayapi_v1.Listener
+	// programmed reports whether the parent is programmed, and listenersProgrammed
+	// which of its listeners are
+	programmed          bool
+	listenersProgrammed []gatewayapi_v1.SectionName
+}
+
+func gatewayParent(gw *gatewayapi_v1.Gateway) routeParent {
+	parent := routeParent{
+		gateway:    gw,
+		kind:       "Gateway",
+		object:     gw.ObjectMeta,
+		listeners:  gw.Spec.Listeners,
+		programmed: gatewayProgrammed(gw),
+	}
+	for _, status := range gw.Status.Listeners {
+		if meta.IsStatusConditionTrue(status.Conditions, string(gatewayapi_v1.ListenerConditionProgrammed)) {
+			parent.listenersProgrammed = append(parent.listenersProgrammed, status.Name)
+		}
+	}
+	return parent
+}
+
+func listenerSetParent(ls *gatewayapi_v1.ListenerSet, gw *gatewayapi_v1.Gateway) routeParent {
+	parent := routeParent{
+		gateway: gw,
+		kind:    "ListenerSet",
+		object:  ls.ObjectMeta,
+		programmed: gatewayProgrammed(gw) &&
+			meta.IsStatusConditionTrue(ls.Status.Conditions, string(gatewayapi_v1.ListenerSetConditionProgrammed)),
+	}
+	for _, entry := range ls.Spec.Listeners {
+		parent.listeners = append(parent.listeners, gatewayapi_v1.Listener{
+			Name:          entry.Name,
+			Hostname:      entry.Hostname,
+			Port:          entry.Port,
+			Protocol:      entry.Protocol,
+			TLS:           entry.TLS,
+			AllowedRoutes: entry.AllowedRoutes,
+		})
+	}
+	for _, status := range ls.Status.Listeners {
+		if meta.IsStatusConditionTrue(status.Conditions, string(gatewayapi_v1.ListenerEntryConditionProgrammed)) {
+			parent.listenersProgrammed = append(parent.listenersProgrammed, status.Name)
+		}
+	}
+	return parent
+}
+
+// indexKey returns the key routes attached to the parent are indexed by, see
+// routeParentIndexFunc
+func (p routeParent) indexKey() string {
+	return parentIndexKey(p.kind, p.object.Namespace, p.object.Name)
+}
+
+func parentIndexKey(kind gatewayapi_v1.Kind, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+}
+
+// resolve returns the parents a parent reference of a route in routeNamespace
+// refers to. ListenerSets are resolved to their Gateway if it allows them.
+func (p parentInformers) resolve(ref gatewayapi_v1.ParentReference, routeNamespace string) (parents []routeParent) {
+	ns := routeNamespace
+	if ref.Namespace != nil {
+		ns = string(*ref.Namespace)
+	}
+	key := fmt.Sprintf("%s/%s", ns, ref.Name)
+
+	switch {
+	case isGatewayRef(ref):
+		gwObjs := p.gateways.byIndex(gatewayUniqueIndex, key)
+		log.Debugf("Found %d matching gateway objects", len(gwObjs))
+		for _, gwObj := range gwObjs {
+			gw, _ := gwObj.(*gatewayapi_v1.Gateway)
+			parents = append(parents, gatewayParent(gw))
+		}
+	case isListenerSetRef(ref):
+		lsObjs := p.listenerSets.byIndex(listenerSetUniqueIndex, key)
+		log.Debugf("Found %d matching listenerSet objects", len(lsObjs))
+		for _, lsObj := range lsObjs {
+			ls, _ := lsObj.(*gatewayapi_v1.ListenerSet)
+			for _, gw := range p.listenerSetGateways(ls) {
+				parents = append(parents, listenerSetParent(ls, gw))
+			}
+		}
+	}
+	return parents
+}
+
+// listenerSetGateways returns the Gateway a ListenerSet attaches to, if the Gateway
+// allows ListenerSets from its namespace
+func (p parentInformers) listenerSetGateways(ls *gatewayapi_v1.ListenerSet) (gateways []*gatewayapi_v1.Gateway) {
+	ref := ls.Spec.ParentRef
+	if (ref.Group != nil && *ref.Group != gatewayapi_v1.GroupName) || (ref.Kind != nil && *ref.Kind != "Gateway") {
+		return nil
+	}
+	ns := ls.Namespace
+	if ref.Namespace != nil {
+		ns = string(*ref.Namespace)
+	}
+
+	for _, gwObj := range p.gateways.byIndex(gatewayUniqueIndex, fmt.Sprintf("%s/%s", ns, ref.Name)) {
+		gw, _ := gwObj.(*gatewayapi_v1.Gateway)
+		if !gatewayAllowsListenerSet(gw, ls.Namespace) {
+			log.Debugf("Skipping listenerSet %s/%s, gateway %s/%s doesn't allow it", ls.Namespace, ls.Name, gw.Namespace, gw.Name)
+			continue
+		}
+		gateways = append(gateways, gw)
+	}
+	return gateways
+}
+
+// gatewayAllowsListenerSet reports whether the Gateway accepts ListenerSets from
+// the given namespace, which it doesn't by default. As for routes, namespace
+// selectors are taken to match.
+func gatewayAllowsListenerSet(gw *gatewayapi_v1.Gateway, namespace string) bool {
+	from := gatewayapi_v1.NamespacesFromNone
+	if gw.Spec.AllowedListeners != nil && gw.Spec.AllowedListeners.Namespaces != nil && gw.Spec.AllowedListeners.Namespaces.From != nil {
+		from = *gw.Spec.AllowedListeners.Namespaces.From
+	}
+	switch from {
+	case gatewayapi_v1.NamespacesFromAll, gatewayapi_v1.NamespacesFromSelector:
+		return true
+	case gatewayapi_v1.NamespacesFromSame:
+		return gw.Namespace == namespace
+	default:
+		return false
+	}
+}
+
+// isListenerSetRef reports whether a parent reference refers to a ListenerSet
+func isListenerSetRef(ref gatewayapi_v1.ParentReference) bool {
+	group := gatewayapi_v1.Group(gatewayapi_v1.GroupName)
+	if ref.Group != nil {
+		group = *ref.Group
+	}
+	return group == gatewayapi_v1.GroupName && ref.Kind != nil && *ref.Kind == "ListenerSet"
+}
+
+// byListenerHostname returns the parents with a listener for one of the index keys
+func (p parentInformers) byListenerHostname(indexKeys []string) (parents []routeParent) {
+	for _, key := range indexKeys {
+		for _, gwObj := range p.gateways.byIndex(gatewayListenerHostnameIndex, strings.ToLower(key)) {
+			gw, _ := gwObj.(*gatewayapi_v1.Gateway)
+			parents = append(parents, gatewayParent(gw))
+		}
+		for _, lsObj := range p.listenerSets.byIndex(listenerSetListenerHostnameIndex, strings.ToLower(key)) {
+			ls, _ := lsObj.(*gatewayapi_v1.ListenerSet)
+			for _, gw := range p.listenerSetGateways(ls) {
+				parents = append(parents, listenerSetParent(ls, gw))
+			}
+		}
+	}
+	return parents
+}
+
+// listenerHostnameKeys returns a function listing the listener hostnames of all
+// Gateways and ListenerSets
+func (p parentInformers) listenerHostnameKeys() func() []string {
+	gatewayKeys := listIndexKeys(gatewayListenerHostnameIndex, p.gateways...)
+	listenerSetKeys := listIndexKeys(listenerSetListenerHostnameIndex, p.listenerSets...)
+	return func() []string {
+		return append(gatewayKeys(), listenerSetKeys()...)
+	}
+}
+
+func initializeListenerSetController(ctx context.Context, ctrl *KubeController) informers {
+	return ctrl.forEachNamespace(func(ns string) cache.SharedIndexInformer {
+		return cache.NewSharedIndexInformer(
+			&cache.ListWatch{
+				ListFunc:  listenerSetLister(ctx, ctrl.gwClient, ns),
+				WatchFunc: listenerSetWatcher(ctx, ctrl.gwClient, ns),
+			},
+			&gatewayapi_v1.ListenerSet{},
+			defaultResyncPeriod,
+			cache.Indexers{
+				listenerSetUniqueIndex:           gatewayIndexFunc,
+				listenerSetListenerHostnameIndex: listenerSetListenerHostnameIndexFunc,
+			},
+		)
+	})
+}
+
+func listenerSetLister(ctx context.Context, c gatewayClient.Interface, ns string) func(metav1.ListOptions) (runtime.Object, error) {
+	return func(opts metav1.ListOptions) (runtime.Object, error) {
+		return c.GatewayV1().ListenerSets(ns).List(ctx, opts)
+	}
+}
+
+func listenerSetWatcher(ctx context.Context, c gatewayClient.Interface, ns string) func(metav1.ListOptions) (watch.Interface, error) {
+	return func(opts metav1.ListOptions) (watch.Interface, error) {
+		return c.GatewayV1().ListenerSets(ns).Watch(ctx, opts)
+	}
+}
+
+// listenerSetListenerHostnameIndexFunc indexes a ListenerSet by the hostnames of its
+// listeners, see gatewayListenerHostnameIndexFunc
+func listenerSetListenerHostnameIndexFunc(obj interface{}) ([]string, error) {
+	ls, ok := obj.(*gatewayapi_v1.ListenerSet)
+	if !ok {
+		return []string{}, nil
+	}
+
+	var hostnames []string
+	for _, listener := range ls.Spec.Listeners {
+		if listener.Hostname == nil || *listener.Hostname == "" {
+			continue
+		}
+		hostname := strings.ToLower(string(*listener.Hostname))
+		if !slices.Contains(hostnames, hostname) {
+			hostnames = append(hostnames, hostname)
+		}
+	}
+	return hostnames, nil
+}
@@ -0,0 +1,122 @@
+package gateway
+
+import (
+	"slices"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	gatewayapi_v1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestListenerSetLookup(t *testing.T) {
+	gatewayIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		gatewayUniqueIndex:           gatewayIndexFunc,
+		gatewayListenerHostnameIndex: gatewayListenerHostnameIndexFunc,
+	})
+	listenerSetIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		listenerSetUniqueIndex:           gatewayIndexFunc,
+		listenerSetListenerHostnameIndex: listenerSetListenerHostnameIndexFunc,
+	})
+	routeIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		httpRouteHostnameIndex: httpRouteHostnameIndexFunc,
+		routeParentIndex:       routeParentIndexFunc,
+	})
+
+	ipAddressType := gatewayapi_v1.IPAddressType
+	fromSame := gatewayapi_v1.NamespacesFromSame
+	newGateway := func(name, addr string, allowed *gatewayapi_v1.FromNamespaces) *gatewayapi_v1.Gateway {
+		gw := &gatewayapi_v1.Gateway{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "infra"},
+			Status: gatewayapi_v1.GatewayStatus{
+				Addresses: []gatewayapi_v1.GatewayStatusAddress{{Type: &ipAddressType, Value: addr}},
+			},
+		}
+		if allowed != nil {
+			gw.Spec.AllowedListeners = &gatewayapi_v1.AllowedListeners{Namespaces: &gatewayapi_v1.ListenerNamespaces{From: allowed}}
+		}
+		return gw
+	}
+	newListenerSet := func(name, namespace, gateway string, hostname gatewayapi_v1.Hostname) *gatewayapi_v1.ListenerSet {
+		gatewayNamespace := gatewayapi_v1.Namespace("infra")
+		return &gatewayapi_v1.ListenerSet{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: gatewayapi_v1.ListenerSetSpec{
+				ParentRef: gatewayapi_v1.ParentGatewayReference{Name: gatewayapi_v1.ObjectName(gateway), Namespace: &gatewayNamespace},
+				Listeners: []gatewayapi_v1.ListenerEntry{{Name: "https", Protocol: gatewayapi_v1.HTTPSProtocolType, Port: 443, Hostname: &hostname}},
+			},
+		}
+	}
+	listenerSetKind := gatewayapi_v1.Kind("ListenerSet")
+	newRoute := func(name, namespace, listenerSet string, hostnames ...gatewayapi_v1.Hostname) *gatewayapi_v1.HTTPRoute {
+		return &gatewayapi_v1.HTTPRoute{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: gatewayapi_v1.HTTPRouteSpec{
+				CommonRouteSpec: gatewayapi_v1.CommonRouteSpec{ParentRefs: []gatewayapi_v1.ParentReference{
+					{Kind: &listenerSetKind, Name: gatewayapi_v1.ObjectName(listenerSet)},
+				}},
+				Hostnames: hostnames,
+			},
+		}
+	}
+
+	for _, gw := range []*gatewayapi_v1.Gateway{
+		newGateway("shared", "192.0.2.1", &fromSame),
+		newGateway("closed", "192.0.2.2", nil),
+	} {
+		if err := gatewayIndexer.Add(gw); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, ls := range []*gatewayapi_v1.ListenerSet{
+		newListenerSet("team-a", "infra", "shared", "*.team-a.example.com"),
+		// the Gateway only allows ListenerSets from its own namespace
+		newListenerSet("team-b", "team-b", "shared", "*.team-b.example.com"),
+		newListenerSet("team-c", "infra", "closed", "*.team-c.example.com"),
+	} {
+		if err := listenerSetIndexer.Add(ls); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, route := range []*gatewayapi_v1.HTTPRoute{
+		newRoute("app", "infra", "team-a", "app.team-a.example.com"),
+		newRoute("inherited", "infra", "team-a"),
+		newRoute("app", "team-b", "team-b", "app.team-b.example.com"),
+		newRoute("app", "team-c", "team-c", "app.team-c.example.com"),
+	} {
+		if err := routeIndexer.Add(route); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	parents := parentInformers{
+		gateways:     informers{&fakeSharedIndexInformer{indexer: gatewayIndexer}},
+		listenerSets: informers{&fakeSharedIndexInformer{indexer: listenerSetIndexer}},
+	}
+	routeLookup := lookupHttpRouteIndex(informers{&fakeSharedIndexInformer{indexer: routeIndexer}}, parents, nil, false)
+	gatewayLookup := lookupGatewayIndex(parents, nil)
+
+	tests := []struct {
+		lookup    lookupFunc
+		indexKeys []string
+		expected  []string
+	}{
+		{routeLookup, []string{"app.team-a.example.com"}, []string{"192.0.2.1"}},
+		// the route inherits the hostname of the ListenerSet listener
+		{routeLookup, []string{"*.team-a.example.com"}, []string{"192.0.2.1"}},
+		{routeLookup, []string{"app.team-b.example.com"}, nil},
+		{routeLookup, []string{"app.team-c.example.com"}, nil},
+		{gatewayLookup, []string{"*.team-a.example.com"}, []string{"192.0.2.1"}},
+		{gatewayLookup, []string{"*.team-c.example.com"}, nil},
+	}
+
+	for i, tc := range tests {
+		var got []string
+		for _, addr := range tc.lookup(tc.indexKeys).addrs {
+			got = append(got, addr.String())
+		}
+		if !slices.Equal(got, tc.expected) {
+			t.Errorf("Test %d: expected addresses %v, got %v", i, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const subsystem = "k8s_gateway"
+
+var (
+	// resourceObjects is the number of objects held by the informers of a resource type.
+	resourceObjects = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "resource_objects",
+		Help:      "The number of objects held by the informers of a resource type.",
+	}, []string{"resource"})
+
+	// informerSynced reports whether the informers of a resource type have synced.
+	informerSynced = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "informer_synced",
+		Help:      "Whether the informers of a resource type have synced (1) or not (0).",
+	}, []string{"resource"})
+
+	// lookupCount counts the lookups per resource and whether they found a record.
+	lookupCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "lookups_total",
+		Help:      "Counter of resource lookups, partitioned by resource and result (hit or miss).",
+	}, []string{"resource", "result"})
+
+	// lookupDuration is the time it takes to look up a name in a resource.
+	lookupDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:                   plugin.Namespace,
+		Subsystem:                   subsystem,
+		Name:                        "lookup_duration_seconds",
+		Buckets:                     plugin.TimeBuckets,
+		NativeHistogramBucketFactor: plugin.NativeHistogramBucketFactor,
+		Help:                        "Histogram of the time (in seconds) each resource lookup took.",
+	}, []string{"resource"})
+
+	// hostnameLookupFailures counts the load balancer hostnames that could not be resolved.
+	hostnameLookupFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "hostname_lookup_failures_total",
+		Help:      "Counter of load balancer hostnames that failed to resolve.",
+	})
+
+	// fallthroughCount counts the queries passed on to the next plugin.
+	fallthroughCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "fallthrough_total",
+		Help:      "Counter of queries passed on to the next plugin.",
+	}, []string{"server"})
+)
+
+// observeLookup records the lookup of a query name in a resource, which started at
+// start and found a record if hit is set.
+func observeLookup(resource string, start time.Time, hit bool) {
+	lookupDuration.WithLabelValues(resource).Observe(time.Since(start).Seconds())
+	if hit {
+		lookupCount.WithLabelValues(resource, "hit").Inc()
+		return
+	}
+	lookupCount.WithLabelValues(resource, "miss").Inc()
+}
@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/fall"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestLookupMetrics(t *testing.T) {
+	gw := newGateway()
+	gw.Zones = []string{"example.com."}
+	setupLookupFuncs(gw)
+
+	ingressHits := testutil.ToFloat64(lookupCount.WithLabelValues("Ingress", "hit"))
+	ingressMisses := testutil.ToFloat64(lookupCount.WithLabelValues("Ingress", "miss"))
+	serviceHits := testutil.ToFloat64(lookupCount.WithLabelValues("Service", "hit"))
+
+	// found by the Ingress lookup, the Service lookup isn't reached
+	gw.queryMatchingAddresses([][]string{{"domain.example.com"}})
+	// found by the Service lookup only
+	gw.queryMatchingAddresses([][]string{{"svc1.ns1"}})
+	// names looked up internally, e.g. CNAME targets or zone transfers, aren't counted
+	gw.getMatchingAddresses([][]string{{"domain.example.com"}})
+	gw.lookupResource("Ingress").keys = func() []string { return []string{"domain.example.com"} }
+	gw.zoneRecords("example.com.")
+
+	if got := testutil.ToFloat64(lookupCount.WithLabelValues("Ingress", "hit")) - ingressHits; got != 1 {
+		t.Errorf("expected 1 Ingress hit, got %v", got)
+	}
+	if got := testutil.ToFloat64(lookupCount.WithLabelValues("Ingress", "miss")) - ingressMisses; got != 1 {
+		t.Errorf("expected 1 Ingress miss, got %v", got)
+	}
+	if got := testutil.ToFloat64(lookupCount.WithLabelValues("Service", "hit")) - serviceHits; got != 1 {
+		t.Errorf("expected 1 Service hit, got %v", got)
+	}
+}
+
+func TestFallthroughMetrics(t *testing.T) {
+	gw := newGateway()
+	gw.Zones = []string{"example.com."}
+	gw.Next = test.NextHandler(dns.RcodeSuccess, nil)
+	gw.Controller = &KubeController{hasSynced: true}
+	gw.Fall = fall.F{Zones: []string{"example.com."}}
+	setupLookupFuncs(gw)
+
+	before := testutil.ToFloat64(fallthroughCount.WithLabelValues(""))
+	for _, qname := range []string{"domain.example.com.", "nonexistent.example.com."} {
+		r := test.Case{Qname: qname, Qtype: dns.TypeA}.Msg()
+		if _, err := gw.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), r); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if got := testutil.ToFloat64(fallthroughCount.WithLabelValues("")) - before; got != 1 {
+		t.Errorf("expected 1 fallthrough, got %v", got)
+	}
+}
+
+func TestUpdateObjectCounts(t *testing.T) {
+	newInformer := func(names ...string) cache.SharedIndexInformer {
+		informer := cache.NewSharedIndexInformer(&cache.ListWatch{}, &core.Service{}, defaultResyncPeriod, cache.Indexers{})
+		for _, name := range names {
+			if err := informer.GetStore().Add(&core.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns1"}}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return informer
+	}
+
+	ctrl := &KubeController{}
+	ctrl.addController("Service", newInformer("svc1", "svc2"))
+	ctrl.addController("Service", newInformer("svc3"))
+	ctrl.addController("EndpointSlice", newInformer())
+	ctrl.updateObjectCounts()
+
+	if got := testutil.ToFloat64(resourceObjects.WithLabelValues("Service")); got != 3 {
+		t.Errorf("expected 3 Service objects across informers, got %v", got)
+	}
+	if got := testutil.ToFloat64(resourceObjects.WithLabelValues("EndpointSlice")); got != 0 {
+		t.Errorf("expected 0 EndpointSlice objects, got %v", got)
+	}
+}
@@ -0,0 +1,163 @@
+package gateway
+
+import (
+	"context"
+	"slices"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// informers holds the informers of one resource type, one per watched namespace
+type informers []cache.SharedIndexInformer
+
+// byIndex merges the objects matching the indexed value across all informers
+func (i informers) byIndex(index, value string) (objs []interface{}) {
+	for _, informer := range i {
+		obj, _ := informer.GetIndexer().ByIndex(index, value)
+		objs = append(objs, obj...)
+	}
+	return objs
+}
+
+// addIndexers adds indexers to all informers, which must not have been started yet
+func (i informers) addIndexers(indexers cache.Indexers) {
+	for _, informer := range i {
+		if err := informer.AddIndexers(indexers); err != nil {
+			log.Warningf("failed to add indexers: %s", err)
+		}
+	}
+}
+
+// forEachNamespace builds an informer for every watched namespace. With a namespace
+// label selector a single informer watches all namespaces, whose objects are only
+// visible while their namespace is selected, see namespaceFilter.
+func (ctrl *KubeController) forEachNamespace(newInformer func(ns string) cache.SharedIndexInformer) (result informers) {
+	for _, ns := range ctrl.namespaces {
+		informer := newInformer(ns)
+		if ctrl.namespaceFilter != nil {
+			informer = &filteredInformer{SharedIndexInformer: informer, allows: ctrl.namespaceFilter.allows}
+		}
+		result = append(result, informer)
+	}
+	return result
+}
+
+// watchedNamespaces returns the namespaces the informers are restricted to. Without
+// any namespace filter, or with a namespace label selector, all namespaces are
+// watched by a single informer per resource.
+func watchedNamespaces(filters ResourceFilters) []string {
+	if len(filters.namespaces) == 0 || filters.namespaceLabelSelector != "" {
+		return []string{core.NamespaceAll}
+	}
+
+	namespaces := slices.Clone(filters.namespaces)
+	slices.Sort(namespaces)
+	namespaces = slices.Compact(namespaces)
+	log.Infof("Watching namespaces: %v", namespaces)
+	return namespaces
+}
+
+// namespaceFilter selects the namespaces listed in namespaces and the ones matching
+// the namespace label selector. The matching namespaces are watched, so that
+// namespaces labelled or unlabelled later are picked up as they change.
+type namespaceFilter struct {
+	namespaces []string
+	selected   cache.SharedIndexInformer
+}
+
+// newNamespaceFilter returns the filter of the namespace label selector, nil if
+// none is set
+func newNamespaceFilter(ctx context.Context, c kubernetes.Interface, filters ResourceFilters) *namespaceFilter {
+	if filters.namespaceLabelSelector == "" {
+		return nil
+	}
+	log.Infof("Watching namespaces %v and the namespaces matching %q", filters.namespaces, filters.namespaceLabelSelector)
+	return &namespaceFilter{
+		namespaces: filters.namespaces,
+		selected: cache.NewSharedIndexInformer(
+			&cache.ListWatch{
+				ListFunc:  namespaceLister(ctx, c, filters.namespaceLabelSelector),
+				WatchFunc: namespaceWatcher(ctx, c, filters.namespaceLabelSelector),
+			},
+			&core.Namespace{},
+			defaultResyncPeriod,
+			cache.Indexers{},
+		),
+	}
+}
+
+func namespaceLister(ctx context.Context, c kubernetes.Interface, labelSelector string) func(metav1.ListOptions) (runtime.Object, error) {
+	return func(opts metav1.ListOptions) (runtime.Object, error) {
+		opts.LabelSelector = labelSelector
+		return c.CoreV1().Namespaces().List(ctx, opts)
+	}
+}
+
+func namespaceWatcher(ctx context.Context, c kubernetes.Interface, labelSelector string) func(metav1.ListOptions) (watch.Interface, error) {
+	return func(opts metav1.ListOptions) (watch.Interface, error) {
+		opts.LabelSelector = labelSelector
+		return c.CoreV1().Namespaces().Watch(ctx, opts)
+	}
+}
+
+// allows reports whether objects in namespace ns are published
+func (f *namespaceFilter) allows(ns string) bool {
+	if slices.Contains(f.namespaces, ns) {
+		return true
+	}
+	_, exists, _ := f.selected.GetIndexer().GetByKey(ns)
+	return exists
+}
+
+// filteredInformer hides the objects of the namespaces allows rejects from the
+// lookups, while the informer itself keeps watching them
+type filteredInformer struct {
+	cache.SharedIndexInformer
+	allows func(ns string) bool
+}
+
+func (i *filteredInformer) GetIndexer() cache.Indexer {
+	return &filteredIndexer{Indexer: i.SharedIndexInformer.GetIndexer(), allows: i.allows}
+}
+
+// filteredIndexer filters the objects returned by the lookups used by resources.
+// Index values, e.g. for zone transfers, are still listed for all namespaces, the
+// lookups for them find no object.
+type filteredIndexer struct {
+	cache.Indexer
+	allows func(ns string) bool
+}
+
+func (i *filteredIndexer) filter(objs []interface{}) (result []interface{}) {
+	for _, obj := range objs {
+		if o, ok := obj.(metav1.Object); ok && i.allows(o.GetNamespace()) {
+			result = append(result, obj)
+		}
+	}
+	return result
+}
+
+func (i *filteredIndexer) ByIndex(indexName, indexedValue string) ([]interface{}, error) {
+	objs, err := i.Indexer.ByIndex(indexName, indexedValue)
+	return i.filter(objs), err
+}
+
+func (i *filteredIndexer) List() []interface{} {
+	return i.filter(i.Indexer.List())
+}
+
+func (i *filteredIndexer) GetByKey(key string) (interface{}, bool, error) {
+	obj, exists, err := i.Indexer.GetByKey(key)
+	if !exists || err != nil {
+		return obj, exists, err
+	}
+	if len(i.filter([]interface{}{obj})) == 0 {
+		return nil, false, nil
+	}
+	return obj, true, nil
+}
@@ -0,0 +1,210 @@
+package gateway
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/coredns/caddy"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestNamespacesParsing(t *testing.T) {
+	tests := []struct {
+		input              string
+		shouldErr          bool
+		expectedErr        string
+		expectedNamespaces []string
+		expectedSelector   string
+	}{
+		{
+			input: `k8s_gateway example.org {
+	namespaces team-a team-b
+}`,
+			expectedNamespaces: []string{"team-a", "team-b"},
+		},
+		{
+			input: `k8s_gateway example.org {
+	namespaces team-a
+	namespaces team-b
+}`,
+			expectedNamespaces: []string{"team-a", "team-b"},
+		},
+		{
+			input: `k8s_gateway example.org {
+	namespaceLabelSelector "dns = public"
+}`,
+			expectedSelector: "dns=public",
+		},
+		{
+			input: `k8s_gateway example.org {
+	namespaces
+}`,
+			shouldErr:   true,
+			expectedErr: "requires at least one argument",
+		},
+		{
+			input: `k8s_gateway example.org {
+	namespaces Team_A
+}`,
+			shouldErr:   true,
+			expectedErr: "invalid namespace",
+		},
+		{
+			input: `k8s_gateway example.org {
+	namespaceLabelSelector
+}`,
+			shouldErr:   true,
+			expectedErr: "requires exactly one argument",
+		},
+		{
+			input: `k8s_gateway example.org {
+	namespaceLabelSelector "!!!invalid"
+}`,
+			shouldErr:   true,
+			expectedErr: "invalid namespaceLabelSelector",
+		},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		gw, err := parse(c)
+
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected error for input %s", i, test.input)
+			} else if !strings.Contains(err.Error(), test.expectedErr) {
+				t.Errorf("Test %d: Expected error containing %q, got: %v", i, test.expectedErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Unexpected error for input %s: %v", i, test.input, err)
+			continue
+		}
+		if !slices.Equal(gw.resourceFilters.namespaces, test.expectedNamespaces) {
+			t.Errorf("Test %d: Expected namespaces %v, got %v", i, test.expectedNamespaces, gw.resourceFilters.namespaces)
+		}
+		if gw.resourceFilters.namespaceLabelSelector != test.expectedSelector {
+			t.Errorf("Test %d: Expected selector %q, got %q", i, test.expectedSelector, gw.resourceFilters.namespaceLabelSelector)
+		}
+	}
+}
+
+func TestWatchedNamespaces(t *testing.T) {
+	tests := []struct {
+		filters  ResourceFilters
+		expected []string
+	}{
+		{ResourceFilters{}, []string{core.NamespaceAll}},
+		{ResourceFilters{namespaces: []string{"team-c", "team-a", "team-c"}}, []string{"team-a", "team-c"}},
+		{ResourceFilters{namespaceLabelSelector: "dns=public"}, []string{core.NamespaceAll}},
+		{ResourceFilters{namespaces: []string{"team-a"}, namespaceLabelSelector: "dns=public"}, []string{core.NamespaceAll}},
+	}
+
+	for i, test := range tests {
+		if namespaces := watchedNamespaces(test.filters); !slices.Equal(namespaces, test.expected) {
+			t.Errorf("Test %d: Expected namespaces %v, got %v", i, test.expected, namespaces)
+		}
+	}
+}
+
+func TestNamespaceFilter(t *testing.T) {
+	if filter := newNamespaceFilter(context.TODO(), fake.NewClientset(), ResourceFilters{namespaces: []string{"team-a"}}); filter != nil {
+		t.Errorf("Expected no filter without a namespace label selector")
+	}
+
+	selected := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	filter := &namespaceFilter{namespaces: []string{"team-c"}, selected: &fakeSharedIndexInformer{indexer: selected}}
+	teamA := &core.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"dns": "public"}}}
+	if err := selected.Add(teamA); err != nil {
+		t.Fatal(err)
+	}
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{serviceHostnameIndex: serviceHostnameIndexFunc})
+	for _, ns := range []string{"team-a", "team-b", "team-c"} {
+		svc := &core.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: ns},
+			Spec:       core.ServiceSpec{Type: core.ServiceTypeLoadBalancer},
+		}
+		if err := indexer.Add(svc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	services := informers{&filteredInformer{SharedIndexInformer: &fakeSharedIndexInformer{indexer: indexer}, allows: filter.allows}}
+
+	visible := func() (namespaces []string) {
+		for _, obj := range services[0].GetIndexer().List() {
+			namespaces = append(namespaces, obj.(*core.Service).Namespace)
+		}
+		slices.Sort(namespaces)
+		return namespaces
+	}
+	if namespaces := visible(); !slices.Equal(namespaces, []string{"team-a", "team-c"}) {
+		t.Errorf("Expected Services of team-a and team-c, got %v", namespaces)
+	}
+	if objs := services.byIndex(serviceHostnameIndex, "svc.team-b"); len(objs) != 0 {
+		t.Errorf("Expected no Service of the unselected team-b, got %d", len(objs))
+	}
+	if _, exists, _ := services[0].GetIndexer().GetByKey("team-b/svc"); exists {
+		t.Errorf("Expected team-b/svc to be hidden")
+	}
+
+	// labelling and unlabelling namespaces takes effect without restarting the informers
+	if err := selected.Add(&core.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := selected.Delete(teamA); err != nil {
+		t.Fatal(err)
+	}
+	if namespaces := visible(); !slices.Equal(namespaces, []string{"team-b", "team-c"}) {
+		t.Errorf("Expected Services of team-b and team-c, got %v", namespaces)
+	}
+	if objs := services.byIndex(serviceHostnameIndex, "svc.team-b"); len(objs) != 1 {
+		t.Errorf("Expected the Service of the selected team-b, got %d", len(objs))
+	}
+}
+
+func TestInformersByIndex(t *testing.T) {
+	newInformer := func(services ...*core.Service) cache.SharedIndexInformer {
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{serviceHostnameIndex: serviceHostnameIndexFunc})
+		for _, svc := range services {
+			if err := indexer.Add(svc); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return &fakeSharedIndexInformer{indexer: indexer}
+	}
+	service := func(name, namespace string) *core.Service {
+		return &core.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       core.ServiceSpec{Type: core.ServiceTypeLoadBalancer},
+			Status: core.ServiceStatus{
+				LoadBalancer: core.LoadBalancerStatus{Ingress: []core.LoadBalancerIngress{{IP: "192.0.2.1"}}},
+			},
+		}
+	}
+
+	ctrl := informers{
+		newInformer(service("svc1", "team-a")),
+		newInformer(service("svc2", "team-b")),
+	}
+	if objs := ctrl.byIndex(serviceHostnameIndex, "svc1.team-a"); len(objs) != 1 {
+		t.Errorf("Expected 1 object for svc1.team-a, got %d", len(objs))
+	}
+	if objs := ctrl.byIndex(serviceHostnameIndex, "svc2.team-b"); len(objs) != 1 {
+		t.Errorf("Expected 1 object for svc2.team-b, got %d", len(objs))
+	}
+	if objs := ctrl.byIndex(serviceHostnameIndex, "svc1.team-b"); len(objs) != 0 {
+		t.Errorf("Expected no object for svc1.team-b, got %d", len(objs))
+	}
+
+	result := lookupServiceIndex(ctrl, informers{newInformer()})([]string{"svc2.team-b"})
+	if len(result.addrs) != 1 || result.addrs[0].String() != "192.0.2.1" {
+		t.Errorf("Expected 192.0.2.1 from the team-b informer, got %v", result.addrs)
+	}
+}
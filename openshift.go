@@ -0,0 +1,237 @@
+package gateway
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	openshiftRouteHostnameIndex = "openshiftRouteHostname"
+	// openshiftRouterNamespace holds the Services of the ingress controllers, named
+	// after the router, e.g. router-default
+	openshiftRouterNamespace = "openshift-ingress"
+)
+
+var openshiftCRDClient rest.Interface
+
+var openshiftRouteGroupVersion = schema.GroupVersion{Group: "route.openshift.io", Version: "v1"}
+
+// openshiftRoute holds the fields of a route.openshift.io Route needed to publish
+// it, so that no OpenShift dependency is pulled in.
+type openshiftRoute struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              openshiftRouteSpec   `json:"spec"`
+	Status            openshiftRouteStatus `json:"status,omitempty"`
+}
+
+type openshiftRouteSpec struct {
+	Host string `json:"host,omitempty"`
+}
+
+type openshiftRouteStatus struct {
+	Ingress []openshiftRouteIngress `json:"ingress,omitempty"`
+}
+
+// openshiftRouteIngress is the status of a Route reported by one router
+type openshiftRouteIngress struct {
+	Host                    string                           `json:"host,omitempty"`
+	RouterName              string                           `json:"routerName,omitempty"`
+	RouterCanonicalHostname string                           `json:"routerCanonicalHostname,omitempty"`
+	Conditions              []openshiftRouteIngressCondition `json:"conditions,omitempty"`
+}
+
+type openshiftRouteIngressCondition struct {
+	Type   string               `json:"type"`
+	Status core.ConditionStatus `json:"status"`
+}
+
+type openshiftRouteList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []openshiftRoute `json:"items"`
+}
+
+func (in *openshiftRoute) DeepCopyObject() runtime.Object {
+	out := &openshiftRoute{TypeMeta: in.TypeMeta, Spec: in.Spec}
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	for _, ingress := range in.Status.Ingress {
+		ingress.Conditions = slices.Clone(ingress.Conditions)
+		out.Status.Ingress = append(out.Status.Ingress, ingress)
+	}
+	return out
+}
+
+func (in *openshiftRouteList) DeepCopyObject() runtime.Object {
+	out := &openshiftRouteList{TypeMeta: in.TypeMeta}
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	for i := range in.Items {
+		out.Items = append(out.Items, *in.Items[i].DeepCopyObject().(*openshiftRoute))
+	}
+	return out
+}
+
+func addOpenShiftRouteTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypeWithName(openshiftRouteGroupVersion.WithKind("Route"), &openshiftRoute{})
+	scheme.AddKnownTypeWithName(openshiftRouteGroupVersion.WithKind("RouteList"), &openshiftRouteList{})
+	metav1.AddToGroupVersion(scheme, openshiftRouteGroupVersion)
+	return nil
+}
+
+func newOpenShiftRESTClient(config *rest.Config) (rest.Interface, error) {
+	return newCRDRESTClient(config, openshiftRouteGroupVersion, addOpenShiftRouteTypes)
+}
+
+// initializeOpenShiftRouteController watches Routes, together with the Services of
+// the routers admitting them. Routes are served by the OpenShift API server rather
+// than a CRD, so they are discovered instead.
+func initializeOpenShiftRouteController(ctx context.Context, ctrl *KubeController, gw *Gateway) {
+	if !slices.Contains(dereferenceStrings(gw.ConfiguredResources), "Route") {
+		return
+	}
+	resource := gw.lookupResource("Route")
+	if resource == nil || openshiftCRDClient == nil {
+		return
+	}
+	if !apiServesResource(ctrl.client, openshiftRouteGroupVersion.String(), "routes") {
+		return
+	}
+
+	routeControllers := ctrl.forEachNamespace(func(ns string) cache.SharedIndexInformer {
+		return cache.NewSharedIndexInformer(
+			&cache.ListWatch{
+				ListFunc:  openshiftRouteLister(ctx, ns),
+				WatchFunc: openshiftRouteWatcher(ctx, ns),
+			},
+			&openshiftRoute{},
+			defaultResyncPeriod,
+			cache.Indexers{openshiftRouteHostnameIndex: openshiftRouteHostnameIndexFunc},
+		)
+	})
+	routerServiceController := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc:  serviceLister(ctx, ctrl.client, openshiftRouterNamespace, ""),
+			WatchFunc: serviceWatcher(ctx, ctrl.client, openshiftRouterNamespace, ""),
+		},
+		&core.Service{},
+		defaultResyncPeriod,
+		cache.Indexers{},
+	)
+	resource.lookup = lookupOpenShiftRouteIndex(routeControllers, routerServiceController)
+	resource.keys = listIndexKeys(openshiftRouteHostnameIndex, routeControllers...)
+	ctrl.addController("Route", routeControllers...)
+	// limited to the router Services, so counted apart from the shared Service informers
+	ctrl.addController("RouterService", routerServiceController)
+	log.Infof("Route controller initialized")
+}
+
+func openshiftRouteWatcher(ctx context.Context, ns string) func(metav1.ListOptions) (watch.Interface, error) {
+	return func(opts metav1.ListOptions) (watch.Interface, error) {
+		opts.Watch = true
+		return openshiftCRDClient.Get().
+			Resource("routes").
+			Namespace(ns).
+			VersionedParams(&opts, metav1.ParameterCodec).
+			Watch(ctx)
+	}
+}
+
+func openshiftRouteLister(ctx context.Context, ns string) func(metav1.ListOptions) (runtime.Object, error) {
+	return func(opts metav1.ListOptions) (runtime.Object, error) {
+		return openshiftCRDClient.Get().
+			Resource("routes").
+			Namespace(ns).
+			VersionedParams(&opts, metav1.ParameterCodec).
+			Do(ctx).
+			Get()
+	}
+}
+
+func openshiftRouteHostnameIndexFunc(obj interface{}) ([]string, error) {
+	route, ok := obj.(*openshiftRoute)
+	if !ok {
+		return []string{}, nil
+	}
+
+	if checkIgnoreLabel(route.Labels) {
+		log.Debugf("Ignoring route %s due to %s label", route.Name, ignoreLabelKey)
+		return []string{}, nil
+	}
+
+	var hostnames []string
+	candidates := []string{route.Spec.Host}
+	for _, ingress := range route.Status.Ingress {
+		if routeAdmitted(ingress) {
+			candidates = append(candidates, ingress.Host)
+		}
+	}
+	for _, host := range candidates {
+		hostname := strings.ToLower(host)
+		if hostname == "" || slices.Contains(hostnames, hostname) {
+			continue
+		}
+		log.Debugf("Adding index %s for route %s", hostname, route.Name)
+		hostnames = append(hostnames, hostname)
+	}
+	return hostnames, nil
+}
+
+// routeAdmitted reports whether a router has admitted a Route
+func routeAdmitted(ingress openshiftRouteIngress) bool {
+	for _, cond := range ingress.Conditions {
+		if cond.Type == "Admitted" {
+			return cond.Status == core.ConditionTrue
+		}
+	}
+	return false
+}
+
+// lookupOpenShiftRouteIndex answers with the routers that admitted a Route under
+// the queried host, by their canonical hostname if they report one and by the
+// load balancer of their Service otherwise.
+func lookupOpenShiftRouteIndex(ctrl informers, routerServices cache.SharedIndexInformer) func([]string) lookupResult {
+	return func(indexKeys []string) (result lookupResult) {
+		var objs []interface{}
+		for _, key := range indexKeys {
+			objs = append(objs, ctrl.byIndex(openshiftRouteHostnameIndex, strings.ToLower(key))...)
+		}
+		log.Debugf("Found %d matching route objects", len(objs))
+
+		for _, obj := range objs {
+			route, _ := obj.(*openshiftRoute)
+			result.lowerTTL(annotationTTL(route.ObjectMeta))
+			result.observe(route)
+
+			for _, ingress := range route.Status.Ingress {
+				if !routeAdmitted(ingress) || !slices.ContainsFunc(indexKeys, func(key string) bool { return strings.EqualFold(key, ingress.Host) }) {
+					continue
+				}
+				if ingress.RouterCanonicalHostname != "" {
+					result.hostnames = append(result.hostnames, ingress.RouterCanonicalHostname)
+					continue
+				}
+
+				serviceObj, exists, _ := routerServices.GetIndexer().GetByKey(openshiftRouterNamespace + "/router-" + ingress.RouterName)
+				if !exists {
+					log.Debugf("No service found for router %s of route %s/%s", ingress.RouterName, route.Namespace, route.Name)
+					continue
+				}
+				service, _ := serviceObj.(*core.Service)
+				result.observe(service)
+				addrs, hostnames := fetchServiceLoadBalancerIPs(service.Status.LoadBalancer.Ingress)
+				result.addrs = append(result.addrs, addrs...)
+				result.hostnames = append(result.hostnames, hostnames...)
+			}
+		}
+		return
+	}
+}
@@ -0,0 +1,146 @@
+package gateway
+
+import (
+	"slices"
+	"testing"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func admittedIngress(host, routerName, canonicalHostname string, admitted core.ConditionStatus) openshiftRouteIngress {
+	return openshiftRouteIngress{
+		Host:                    host,
+		RouterName:              routerName,
+		RouterCanonicalHostname: canonicalHostname,
+		Conditions:              []openshiftRouteIngressCondition{{Type: "Admitted", Status: admitted}},
+	}
+}
+
+func TestOpenShiftRouteHostnameIndexFunc(t *testing.T) {
+	tests := []struct {
+		route    *openshiftRoute
+		expected []string
+	}{
+		{
+			route: &openshiftRoute{
+				Spec: openshiftRouteSpec{Host: "App.example.com"},
+				Status: openshiftRouteStatus{Ingress: []openshiftRouteIngress{
+					admittedIngress("app.example.com", "default", "", core.ConditionTrue),
+				}},
+			},
+			expected: []string{"app.example.com"},
+		},
+		{
+			// the host is generated by the router
+			route: &openshiftRoute{
+				Status: openshiftRouteStatus{Ingress: []openshiftRouteIngress{
+					admittedIngress("app-team-a.apps.example.com", "default", "", core.ConditionTrue),
+					admittedIngress("app-team-a.internal.example.com", "internal", "", core.ConditionFalse),
+				}},
+			},
+			expected: []string{"app-team-a.apps.example.com"},
+		},
+		{
+			route: &openshiftRoute{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{ignoreLabelKey: "true"}},
+				Spec:       openshiftRouteSpec{Host: "app.example.com"},
+			},
+		},
+	}
+
+	for i, tc := range tests {
+		got, _ := openshiftRouteHostnameIndexFunc(tc.route)
+		if !slices.Equal(got, tc.expected) {
+			t.Errorf("Test %d: expected index %v, got %v", i, tc.expected, got)
+		}
+	}
+}
+
+func TestLookupOpenShiftRoute(t *testing.T) {
+	routeIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		openshiftRouteHostnameIndex: openshiftRouteHostnameIndexFunc,
+	})
+	serviceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+
+	for _, route := range []*openshiftRoute{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-a"},
+			Spec:       openshiftRouteSpec{Host: "app.example.com"},
+			Status: openshiftRouteStatus{Ingress: []openshiftRouteIngress{
+				admittedIngress("app.example.com", "default", "", core.ConditionTrue),
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "canonical", Namespace: "team-a"},
+			Spec:       openshiftRouteSpec{Host: "canonical.example.com"},
+			Status: openshiftRouteStatus{Ingress: []openshiftRouteIngress{
+				admittedIngress("canonical.example.com", "default", "router-default.apps.example.com", core.ConditionTrue),
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pending", Namespace: "team-a"},
+			Spec:       openshiftRouteSpec{Host: "pending.example.com"},
+		},
+	} {
+		if err := routeIndexer.Add(route); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := serviceIndexer.Add(&core.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "router-default", Namespace: openshiftRouterNamespace},
+		Spec:       core.ServiceSpec{Type: core.ServiceTypeLoadBalancer},
+		Status:     core.ServiceStatus{LoadBalancer: core.LoadBalancerStatus{Ingress: []core.LoadBalancerIngress{{IP: "192.0.2.30"}}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lookup := lookupOpenShiftRouteIndex(
+		informers{&fakeSharedIndexInformer{indexer: routeIndexer}},
+		&fakeSharedIndexInformer{indexer: serviceIndexer},
+	)
+
+	tests := []struct {
+		indexKeys         []string
+		expectedAddrs     []string
+		expectedHostnames []string
+	}{
+		{[]string{"app.example.com"}, []string{"192.0.2.30"}, nil},
+		{[]string{"canonical.example.com"}, nil, []string{"router-default.apps.example.com"}},
+		{[]string{"pending.example.com"}, nil, nil},
+	}
+
+	for i, tc := range tests {
+		result := lookup(tc.indexKeys)
+		var addrs []string
+		for _, addr := range result.addrs {
+			addrs = append(addrs, addr.String())
+		}
+		if !slices.Equal(addrs, tc.expectedAddrs) {
+			t.Errorf("Test %d: expected addresses %v, got %v", i, tc.expectedAddrs, addrs)
+		}
+		if !slices.Equal(result.hostnames, tc.expectedHostnames) {
+			t.Errorf("Test %d: expected hostnames %v, got %v", i, tc.expectedHostnames, result.hostnames)
+		}
+	}
+}
+
+func TestAPIServesResource(t *testing.T) {
+	client := fake.NewClientset()
+	client.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "route.openshift.io/v1", APIResources: []metav1.APIResource{{Name: "routes", Kind: "Route", Namespaced: true}}},
+	}
+
+	if !apiServesResource(client, "route.openshift.io/v1", "routes") {
+		t.Errorf("Expected routes to be served")
+	}
+	if apiServesResource(client, "route.openshift.io/v1", "routers") {
+		t.Errorf("Expected routers not to be served")
+	}
+	if apiServesResource(client, "projectcontour.io/v1", "httpproxies") {
+		t.Errorf("Expected an unknown group version not to be served")
+	}
+}
@@ -0,0 +1,183 @@
+package gateway
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+	"strings"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	podHostnameIndex = "podHostname"
+)
+
+// initializePodController watches Pods, together with the Services their subdomain
+// may refer to
+func initializePodController(ctx context.Context, ctrl *KubeController, gw *Gateway) {
+	if !slices.Contains(dereferenceStrings(gw.ConfiguredResources), "Pod") {
+		return
+	}
+	resource := gw.lookupResource("Pod")
+	if resource == nil {
+		return
+	}
+
+	podControllers := ctrl.forEachNamespace(func(ns string) cache.SharedIndexInformer {
+		return cache.NewSharedIndexInformer(
+			&cache.ListWatch{
+				ListFunc:  podLister(ctx, ctrl.client, ns),
+				WatchFunc: podWatcher(ctx, ctrl.client, ns),
+			},
+			&core.Pod{},
+			defaultResyncPeriod,
+			cache.Indexers{podHostnameIndex: podHostnameIndexFunc},
+		)
+	})
+	serviceControllers := ctrl.sharedServices(ctx, cache.Indexers{})
+	resource.lookup = lookupPodIndex(podControllers, serviceControllers)
+	resource.keys = listIndexKeys(podHostnameIndex, podControllers...)
+	ctrl.addController("Pod", podControllers...)
+	log.Infof("Pod controller initialized")
+}
+
+func podLister(ctx context.Context, c kubernetes.Interface, ns string) func(metav1.ListOptions) (runtime.Object, error) {
+	return func(opts metav1.ListOptions) (runtime.Object, error) {
+		return c.CoreV1().Pods(ns).List(ctx, opts)
+	}
+}
+
+func podWatcher(ctx context.Context, c kubernetes.Interface, ns string) func(metav1.ListOptions) (watch.Interface, error) {
+	return func(opts metav1.ListOptions) (watch.Interface, error) {
+		return c.CoreV1().Pods(ns).Watch(ctx, opts)
+	}
+}
+
+// podHostnameIndexFunc indexes a Pod by its annotated hostnames and, if it sets
+// both spec.hostname and spec.subdomain, by hostname.subdomain.namespace the same
+// way cluster DNS names StatefulSet members. Whether the subdomain is a headless
+// Service is only checked on lookup, as Services may change independently.
+func podHostnameIndexFunc(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*core.Pod)
+	if !ok {
+		return []string{}, nil
+	}
+
+	if checkIgnoreLabel(pod.Labels) {
+		log.Debugf("Ignoring pod %s due to %s label", pod.Name, ignoreLabelKey)
+		return []string{}, nil
+	}
+
+	hostnames := annotatedHostnames(pod.ObjectMeta)
+	if hostname := podSubdomainHostname(pod); hostname != "" && !slices.Contains(hostnames, hostname) {
+		hostnames = append(hostnames, hostname)
+	}
+	if len(hostnames) > 0 {
+		log.Debugf("Adding index %v for pod %s", hostnames, pod.Name)
+	}
+	return hostnames, nil
+}
+
+// podSubdomainHostname returns hostname.subdomain.namespace of a Pod, empty if it
+// doesn't set both
+func podSubdomainHostname(pod *core.Pod) string {
+	if pod.Spec.Hostname == "" || pod.Spec.Subdomain == "" {
+		return ""
+	}
+	return strings.ToLower(pod.Spec.Hostname + "." + pod.Spec.Subdomain + "." + pod.Namespace)
+}
+
+// podReady reports whether a Pod is running and has the Ready condition set
+func podReady(pod *core.Pod) bool {
+	if pod.Status.Phase != core.PodRunning {
+		return false
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == core.PodReady {
+			return cond.Status == core.ConditionTrue
+		}
+	}
+	return false
+}
+
+// headlessService returns the headless Service of the given name in the namespace,
+// nil if there is none
+func headlessService(services informers, namespace, name string) *core.Service {
+	for _, informer := range services {
+		obj, exists, _ := informer.GetIndexer().GetByKey(namespace + "/" + name)
+		if !exists {
+			continue
+		}
+		if service, _ := obj.(*core.Service); service.Spec.ClusterIP == core.ClusterIPNone {
+			return service
+		}
+	}
+	return nil
+}
+
+func lookupPodIndex(ctrl, services informers) func([]string) lookupResult {
+	return func(indexKeys []string) (result lookupResult) {
+		var objs []interface{}
+		for _, key := range indexKeys {
+			objs = append(objs, ctrl.byIndex(podHostnameIndex, strings.ToLower(key))...)
+		}
+		log.Debugf("Found %d matching Pod objects", len(objs))
+
+		seen := make(map[string]struct{})
+		for _, obj := range objs {
+			pod, _ := obj.(*core.Pod)
+			if _, dup := seen[pod.Namespace+"/"+pod.Name]; dup {
+				continue
+			}
+			seen[pod.Namespace+"/"+pod.Name] = struct{}{}
+
+			if !podReady(pod) {
+				log.Debugf("Skipping pod %s/%s, it isn't ready", pod.Namespace, pod.Name)
+				continue
+			}
+
+			// a Pod found by its subdomain name only, which requires a headless Service
+			matchesKey := func(hostname string) bool {
+				return slices.ContainsFunc(indexKeys, func(key string) bool { return strings.EqualFold(key, hostname) })
+			}
+			if !slices.ContainsFunc(annotatedHostnames(pod.ObjectMeta), matchesKey) {
+				service := headlessService(services, pod.Namespace, pod.Spec.Subdomain)
+				if service == nil {
+					log.Debugf("Skipping pod %s/%s, subdomain %s isn't a headless service", pod.Namespace, pod.Name, pod.Spec.Subdomain)
+					continue
+				}
+				result.observe(service)
+			}
+
+			result.lowerTTL(annotationTTL(pod.ObjectMeta))
+			result.observe(pod)
+			result.addrs = append(result.addrs, podAddresses(pod)...)
+		}
+		return
+	}
+}
+
+// podAddresses returns the IPs of a Pod, from status.podIPs or else status.podIP
+func podAddresses(pod *core.Pod) (results []netip.Addr) {
+	ips := []string{pod.Status.PodIP}
+	if len(pod.Status.PodIPs) > 0 {
+		ips = nil
+		for _, podIP := range pod.Status.PodIPs {
+			ips = append(ips, podIP.IP)
+		}
+	}
+	for _, ip := range ips {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			continue
+		}
+		results = append(results, addr)
+	}
+	return
+}
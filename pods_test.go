@@ -0,0 +1,121 @@
+package gateway
+
+import (
+	"slices"
+	"testing"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestLookupPod(t *testing.T) {
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		podHostnameIndex: podHostnameIndexFunc,
+	})
+	serviceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+
+	ready := core.PodStatus{
+		Phase:      core.PodRunning,
+		Conditions: []core.PodCondition{{Type: core.PodReady, Status: core.ConditionTrue}},
+	}
+	withIPs := func(status core.PodStatus, ips ...string) core.PodStatus {
+		status.PodIP = ips[0]
+		for _, ip := range ips {
+			status.PodIPs = append(status.PodIPs, core.PodIP{IP: ip})
+		}
+		return status
+	}
+
+	for _, pod := range []*core.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "broker",
+				Namespace:   "team-a",
+				Annotations: map[string]string{hostnameAnnotationKey: "broker.example.com"},
+			},
+			Status: withIPs(ready, "192.0.2.70", "2001:db8::70"),
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-0", Namespace: "team-a"},
+			Spec:       core.PodSpec{Hostname: "db-0", Subdomain: "db"},
+			Status:     withIPs(ready, "10.0.0.1"),
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-1", Namespace: "team-a"},
+			Spec:       core.PodSpec{Hostname: "db-1", Subdomain: "db"},
+			Status: withIPs(core.PodStatus{
+				Phase:      core.PodRunning,
+				Conditions: []core.PodCondition{{Type: core.PodReady, Status: core.ConditionFalse}},
+			}, "10.0.0.2"),
+		},
+		{
+			// the subdomain is a Service with a cluster IP
+			ObjectMeta: metav1.ObjectMeta{Name: "web-0", Namespace: "team-a"},
+			Spec:       core.PodSpec{Hostname: "web-0", Subdomain: "web"},
+			Status:     withIPs(ready, "10.0.0.3"),
+		},
+		{
+			// no Service exists for the subdomain
+			ObjectMeta: metav1.ObjectMeta{Name: "cache-0", Namespace: "team-a"},
+			Spec:       core.PodSpec{Hostname: "cache-0", Subdomain: "cache"},
+			Status:     withIPs(ready, "10.0.0.4"),
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "ignored",
+				Namespace:   "team-a",
+				Labels:      map[string]string{ignoreLabelKey: "true"},
+				Annotations: map[string]string{hostnameAnnotationKey: "ignored.example.com"},
+			},
+			Status: withIPs(ready, "192.0.2.71"),
+		},
+	} {
+		if err := podIndexer.Add(pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, service := range []*core.Service{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "team-a"},
+			Spec:       core.ServiceSpec{ClusterIP: core.ClusterIPNone},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "team-a"},
+			Spec:       core.ServiceSpec{ClusterIP: "10.96.0.10"},
+		},
+	} {
+		if err := serviceIndexer.Add(service); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lookup := lookupPodIndex(
+		informers{&fakeSharedIndexInformer{indexer: podIndexer}},
+		informers{&fakeSharedIndexInformer{indexer: serviceIndexer}},
+	)
+
+	tests := []struct {
+		indexKeys []string
+		expected  []string
+	}{
+		{[]string{"broker.example.com", "broker"}, []string{"192.0.2.70", "2001:db8::70"}},
+		{[]string{"db-0.db.team-a.example.com", "db-0.db.team-a"}, []string{"10.0.0.1"}},
+		{[]string{"db-1.db.team-a.example.com", "db-1.db.team-a"}, nil},
+		{[]string{"web-0.web.team-a.example.com", "web-0.web.team-a"}, nil},
+		{[]string{"cache-0.cache.team-a.example.com", "cache-0.cache.team-a"}, nil},
+		{[]string{"ignored.example.com", "ignored"}, nil},
+	}
+
+	for i, tc := range tests {
+		var got []string
+		for _, addr := range lookup(tc.indexKeys).addrs {
+			got = append(got, addr.String())
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tc.expected) {
+			t.Errorf("Test %d: expected addresses %v, got %v", i, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package gateway
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/request"
+	"github.com/miekg/dns"
+)
+
+// reverseIndex maps every published address to the hostnames it is published under.
+// It is rebuilt from the resource lookups on the first PTR query after any of the
+// watched objects has changed.
+type reverseIndex struct {
+	mu      sync.Mutex
+	dirty   bool
+	entries map[netip.Addr]reverseEntry
+}
+
+// reverseEntry holds the hostnames an address is published under, the lowest TTL
+// they are answered with and the highest resourceVersion of the objects publishing it
+type reverseEntry struct {
+	names    []string
+	ttl      uint32
+	revision uint64
+}
+
+func newReverseIndex() *reverseIndex {
+	return &reverseIndex{dirty: true}
+}
+
+// invalidate marks the index to be rebuilt on its next use
+func (ri *reverseIndex) invalidate() {
+	ri.mu.Lock()
+	ri.dirty = true
+	ri.mu.Unlock()
+}
+
+// lookup returns the hostnames addr is published under
+func (ri *reverseIndex) lookup(gw *Gateway, addr netip.Addr) reverseEntry {
+	return ri.all(gw)[addr]
+}
+
+// all returns the whole index. The returned map must not be modified.
+func (ri *reverseIndex) all(gw *Gateway) map[netip.Addr]reverseEntry {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+
+	if ri.dirty {
+		ri.entries = gw.buildReverseIndex()
+		ri.dirty = false
+	}
+	return ri.entries
+}
+
+// buildReverseIndex walks all index keys of all resources and records the addresses
+// each resulting hostname is answered with. Wildcard keys are skipped as they have
+// no single name to point to. Load balancer hostnames are skipped as well, their
+// addresses belong to the cloud provider's zone, and so are keys inside the reverse
+// zones themselves, e.g. of PTR records.
+func (gw *Gateway) buildReverseIndex() map[netip.Addr]reverseEntry {
+	forwardZones := gw.forwardZones()
+
+	seen := make(map[string]struct{})
+	entries := make(map[netip.Addr]reverseEntry)
+	for _, resource := range gw.Resources {
+		for _, key := range resource.keys() {
+			if key == "" || strings.HasPrefix(key, "*") || isReverseZone(dns.Fqdn(key)) {
+				continue
+			}
+			for _, name := range indexKeyNames(key, forwardZones) {
+				if _, dup := seen[name]; dup {
+					continue
+				}
+				seen[name] = struct{}{}
+
+				zone := plugin.Zones(forwardZones).Matches(name)
+				result := gw.forwardResult(name, zone)
+				ttl := gw.recordTTL(result)
+				for _, addr := range result.addrs {
+					entry := entries[addr]
+					if len(entry.names) == 0 || ttl < entry.ttl {
+						entry.ttl = ttl
+					}
+					if !slices.Contains(entry.names, name) {
+						entry.names = append(entry.names, name)
+					}
+					entry.revision = max(entry.revision, result.revision)
+					entries[addr] = entry
+				}
+			}
+		}
+	}
+
+	for addr := range entries {
+		slices.Sort(entries[addr].names)
+	}
+	log.Debugf("rebuilt reverse index with %d addresses", len(entries))
+	return entries
+}
+
+// forwardResult returns the result a forward query for name is answered with,
+// honouring the priority of the configured resources.
+func (gw *Gateway) forwardResult(name, zone string) lookupResult {
+	indexKeys := gw.getQueryIndexKeys(name, zone)
+	for _, resource := range gw.Resources {
+		if result := resource.lookup(indexKeys); !result.empty() {
+			return result
+		}
+	}
+	return lookupResult{}
+}
+
+// forwardZones returns the configured zones that are not reverse zones
+func (gw *Gateway) forwardZones() (zones []string) {
+	for _, zone := range gw.Zones {
+		if !isReverseZone(zone) {
+			zones = append(zones, zone)
+		}
+	}
+	return zones
+}
+
+// isReverseZone reports whether zone is inside in-addr.arpa. or ip6.arpa.
+func isReverseZone(zone string) bool {
+	return dns.IsSubDomain(dnsutil.IP4arpa[1:], zone) || dns.IsSubDomain(dnsutil.IP6arpa[1:], zone)
+}
+
+// indexKeyNames returns the fully qualified names an index key is served under.
+// Keys that are already inside a forward zone are served as they are, all other
+// keys (e.g. name.namespace of a Service) are served under every forward zone.
+func indexKeyNames(key string, forwardZones []string) []string {
+	fqdn := dns.Fqdn(strings.ToLower(key))
+	if plugin.Zones(forwardZones).Matches(fqdn) != "" {
+		return []string{fqdn}
+	}
+
+	names := make([]string, 0, len(forwardZones))
+	for _, zone := range forwardZones {
+		names = append(names, dnsutil.Join(strings.ToLower(key), zone))
+	}
+	return names
+}
+
+// serveReverse answers queries inside a configured reverse zone with the
+// hostnames the queried address is published under.
+func (gw *Gateway) serveReverse(ctx context.Context, state request.Request) (int, error) {
+	var names []string
+	var ttl uint32
+	if addr, err := netip.ParseAddr(dnsutil.ExtractAddressFromReverse(strings.ToLower(state.Name()))); err == nil {
+		entry := gw.reverse.lookup(gw, addr)
+		names, ttl = entry.names, entry.ttl
+	}
+	// PTR records published explicitly by DNSEndpoints
+	if resource := gw.lookupResource("DNSEndpoint"); resource != nil {
+		result := resource.lookup(gw.getQueryIndexKeys(state.Name(), state.Zone))
+		if ptrs := result.recordsOfType(dns.TypePTR); len(ptrs) > 0 {
+			if endpointTTL := gw.recordTTL(result); len(names) == 0 || endpointTTL < ttl {
+				ttl = endpointTTL
+			}
+			for _, rr := range ptrs {
+				if ptr := rr.(*dns.PTR).Ptr; !slices.Contains(names, ptr) {
+					names = append(slices.Clone(names), ptr)
+				}
+			}
+		}
+	}
+	log.Debugf("computed reverse names %v", names)
+
+	isRootZoneQuery := strings.EqualFold(state.Name(), state.Zone)
+	// names above published addresses exist without records of their own
+	exists := len(names) > 0 || isRootZoneQuery || gw.isReverseNonTerminal(state.Name())
+	if !exists && gw.Fall.Through(state.Name()) {
+		fallthroughCount.WithLabelValues(metrics.WithServer(ctx)).Inc()
+		return plugin.NextOrFailure(gw.Name(), gw.Next, ctx, state.W, state.Req)
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(state.Req)
+	m.Authoritative = true
+
+	switch {
+	case isRootZoneQuery && state.QType() == dns.TypeSOA:
+		m.Answer = []dns.RR{gw.soa(state)}
+	case isRootZoneQuery && state.QType() == dns.TypeNS:
+		// the same nameservers as the forward zones
+		m.Answer = gw.nameservers(state)
+		for _, rr := range gw.ExternalAddrFunc(state) {
+			rr.Header().Ttl = gw.ttlSOA
+			m.Extra = append(m.Extra, rr)
+		}
+	case isRootZoneQuery:
+		m.Ns = []dns.RR{gw.soa(state)}
+	case !exists:
+		m.Rcode = dns.RcodeNameError
+		m.Ns = []dns.RR{gw.soa(state)}
+	case state.QType() == dns.TypePTR && len(names) > 0:
+		m.Answer = gw.PTR(state.Name(), ttl, names)
+	default:
+		m.Ns = []dns.RR{gw.soa(state)}
+	}
+
+	if err := state.W.WriteMsg(m); err != nil {
+		log.Errorf("failed to send a response: %s", err)
+	}
+
+	return dns.RcodeSuccess, nil
+}
+
+// isReverseNonTerminal reports whether name is an empty non-terminal of a reverse
+// zone, an ancestor of the name of a published address or of a DNSEndpoint in it
+func (gw *Gateway) isReverseNonTerminal(name string) bool {
+	name = strings.ToLower(name)
+	for addr := range gw.reverse.all(gw) {
+		if dns.IsSubDomain(name, reverseName(addr)) {
+			return true
+		}
+	}
+	if resource := gw.lookupResource("DNSEndpoint"); resource != nil {
+		for _, key := range resource.keys() {
+			if key != "" && dns.IsSubDomain(name, dns.Fqdn(strings.ToLower(key))) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PTR returns a record per hostname
+func (gw *Gateway) PTR(name string, ttl uint32, hostnames []string) (records []dns.RR) {
+	for _, hostname := range hostnames {
+		records = append(records, &dns.PTR{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: ttl}, Ptr: hostname})
+	}
+	return records
+}
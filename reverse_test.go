@@ -0,0 +1,188 @@
+package gateway
+
+import (
+	"context"
+	"maps"
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+)
+
+func TestPluginReverse(t *testing.T) {
+	ctrl := &KubeController{hasSynced: true}
+
+	gw := newGateway()
+	gw.Zones = []string{"example.com.", "192.in-addr.arpa.", "ip6.arpa."}
+	gw.Next = test.NextHandler(dns.RcodeSuccess, nil)
+	gw.ExternalAddrFunc = gw.SelfAddress
+	gw.Controller = ctrl
+	setupLookupFuncs(gw)
+	gw.lookupResource("Ingress").keys = func() []string { return slices.Collect(maps.Keys(testIngressIndexes)) }
+	gw.lookupResource("Service").keys = func() []string { return slices.Collect(maps.Keys(testServiceIndexes)) }
+
+	ctx := context.TODO()
+	for i, tc := range testsReverse {
+		r := tc.Msg()
+		w := dnstest.NewRecorder(&test.ResponseWriter{})
+
+		_, err := gw.ServeDNS(ctx, w, r)
+		if err != tc.Error {
+			t.Errorf("Test %d expected no error, got %v", i, err)
+			return
+		}
+
+		resp := w.Msg
+		if resp == nil {
+			t.Fatalf("Test %d, got nil message and no error for %q", i, r.Question[0].Name)
+		}
+		if err = test.SortAndCheck(resp, tc); err != nil {
+			t.Errorf("Test %d failed with error: %v", i, err)
+		}
+	}
+}
+
+func TestReverseIndexInvalidate(t *testing.T) {
+	gw := newGateway()
+	gw.Zones = []string{"example.com."}
+	addrs := map[string][]netip.Addr{"svc1.ns1": {netip.MustParseAddr("192.0.2.1")}}
+	gw.lookupResource("Service").lookup = func(keys []string) (result lookupResult) {
+		for _, key := range keys {
+			result.addrs = append(result.addrs, addrs[key]...)
+		}
+		return result
+	}
+	gw.lookupResource("Service").keys = func() []string { return slices.Collect(maps.Keys(addrs)) }
+
+	if names := gw.reverse.lookup(gw, netip.MustParseAddr("192.0.2.1")).names; !slices.Equal(names, []string{"svc1.ns1.example.com."}) {
+		t.Errorf("expected [svc1.ns1.example.com.], got %v", names)
+	}
+
+	addrs["svc2.ns1"] = []netip.Addr{netip.MustParseAddr("192.0.2.2")}
+	if names := gw.reverse.lookup(gw, netip.MustParseAddr("192.0.2.2")).names; len(names) != 0 {
+		t.Errorf("expected the index to be cached until invalidated, got %v", names)
+	}
+
+	gw.reverse.invalidate()
+	if names := gw.reverse.lookup(gw, netip.MustParseAddr("192.0.2.2")).names; !slices.Equal(names, []string{"svc2.ns1.example.com."}) {
+		t.Errorf("expected [svc2.ns1.example.com.], got %v", names)
+	}
+}
+
+func TestReverseTTL(t *testing.T) {
+	gw := newGateway()
+	gw.Zones = []string{"example.com.", "192.in-addr.arpa."}
+	gw.Controller = &KubeController{hasSynced: true}
+	results := map[string]lookupResult{
+		"svc1.ns1": {addrs: []netip.Addr{netip.MustParseAddr("192.0.2.1")}, ttl: 30},
+		"svc2.ns1": {addrs: []netip.Addr{netip.MustParseAddr("192.0.2.1")}, ttl: 5},
+	}
+	gw.lookupResource("Service").lookup = func(keys []string) (result lookupResult) {
+		for _, key := range keys {
+			result = result.merge(results[key])
+		}
+		return result
+	}
+	gw.lookupResource("Service").keys = func() []string { return slices.Collect(maps.Keys(results)) }
+
+	r := new(dns.Msg)
+	r.SetQuestion("1.2.0.192.in-addr.arpa.", dns.TypePTR)
+	w := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := gw.ServeDNS(context.TODO(), w, r); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.Msg.Answer) != 2 {
+		t.Fatalf("expected 2 PTR records, got %v", w.Msg.Answer)
+	}
+	for _, rr := range w.Msg.Answer {
+		if rr.Header().Ttl != 5 {
+			t.Errorf("expected the lowest TTL of the published names, got %s", rr)
+		}
+	}
+}
+
+var testsReverse = []test.Case{
+	// Service address | Test 0
+	{
+		Qname: "1.1.0.192.in-addr.arpa.", Qtype: dns.TypePTR, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.PTR("1.1.0.192.in-addr.arpa.  60  IN  PTR  svc1.ns1.example.com."),
+		},
+	},
+	// Ingress address of a fully qualified key | Test 1
+	{
+		Qname: "1.0.0.192.in-addr.arpa.", Qtype: dns.TypePTR, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.PTR("1.0.0.192.in-addr.arpa.  60  IN  PTR  domain.example.com."),
+		},
+	},
+	// Address shadowed by a resource with higher priority | Test 2
+	{
+		Qname: "2.1.0.192.in-addr.arpa.", Qtype: dns.TypePTR, Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{
+			test.SOA("192.in-addr.arpa.  60  IN  SOA dns1.kube-system.192.in-addr.arpa. hostmaster.dns1.kube-system.192.in-addr.arpa. 1499347823 7200 1800 86400 5"),
+		},
+	},
+	// Address only published under a wildcard | Test 3
+	{
+		Qname: "6.0.0.192.in-addr.arpa.", Qtype: dns.TypePTR, Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{
+			test.SOA("192.in-addr.arpa.  60  IN  SOA dns1.kube-system.192.in-addr.arpa. hostmaster.dns1.kube-system.192.in-addr.arpa. 1499347823 7200 1800 86400 5"),
+		},
+	},
+	// IPv6 address | Test 4
+	{
+		Qname: "0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.1.0.0.0.a.9.8.7.6.5.4.3.2.1.d.f.ip6.arpa.", Qtype: dns.TypePTR, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.PTR("0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.1.0.0.0.a.9.8.7.6.5.4.3.2.1.d.f.ip6.arpa.  60  IN  PTR  svc1.ns1.example.com."),
+		},
+	},
+	// Non-PTR query for an existing name | Test 5
+	{
+		Qname: "1.1.0.192.in-addr.arpa.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Ns: []dns.RR{
+			test.SOA("192.in-addr.arpa.  60  IN  SOA dns1.kube-system.192.in-addr.arpa. hostmaster.dns1.kube-system.192.in-addr.arpa. 1499347823 7200 1800 86400 5"),
+		},
+	},
+	// SOA of the reverse zone | Test 6
+	{
+		Qname: "192.in-addr.arpa.", Qtype: dns.TypeSOA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.SOA("192.in-addr.arpa.  60  IN  SOA dns1.kube-system.192.in-addr.arpa. hostmaster.dns1.kube-system.192.in-addr.arpa. 1499347823 7200 1800 86400 5"),
+		},
+	},
+	// NS of the reverse zone | Test 7
+	{
+		Qname: "192.in-addr.arpa.", Qtype: dns.TypeNS, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.NS("192.in-addr.arpa.  60  IN  NS  dns1.kube-system.192.in-addr.arpa."),
+		},
+		Extra: []dns.RR{
+			test.A("dns1.kube-system.192.in-addr.arpa.  60  IN  A  192.0.1.53"),
+		},
+	},
+	// Other types at the apex of the reverse zone | Test 8
+	{
+		Qname: "192.in-addr.arpa.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Ns: []dns.RR{
+			test.SOA("192.in-addr.arpa.  60  IN  SOA dns1.kube-system.192.in-addr.arpa. hostmaster.dns1.kube-system.192.in-addr.arpa. 1499347823 7200 1800 86400 5"),
+		},
+	},
+	// Empty non-terminal above a published address | Test 9
+	{
+		Qname: "0.0.192.in-addr.arpa.", Qtype: dns.TypePTR, Rcode: dns.RcodeSuccess,
+		Ns: []dns.RR{
+			test.SOA("192.in-addr.arpa.  60  IN  SOA dns1.kube-system.192.in-addr.arpa. hostmaster.dns1.kube-system.192.in-addr.arpa. 1499347823 7200 1800 86400 5"),
+		},
+	},
+	// Name above no published address | Test 10
+	{
+		Qname: "9.0.192.in-addr.arpa.", Qtype: dns.TypePTR, Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{
+			test.SOA("192.in-addr.arpa.  60  IN  SOA dns1.kube-system.192.in-addr.arpa. hostmaster.dns1.kube-system.192.in-addr.arpa. 1499347823 7200 1800 86400 5"),
+		},
+	},
+}
@@ -0,0 +1,91 @@
+package gateway
+
+import (
+	"slices"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	gatewayapi_v1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// routeAccepted reports whether the Gateway controller accepted the route for the
+// given parent reference and resolved all of its references. Several controllers
+// may report on the same parent, e.g. while a GatewayClass changes hands, so every
+// entry for it must agree rather than whichever comes first.
+func routeAccepted(status *gatewayapi_v1.RouteStatus, ref gatewayapi_v1.ParentReference, routeNamespace string) bool {
+	found := false
+	for _, parent := range status.Parents {
+		if !sameParentRef(parent.ParentRef, ref, routeNamespace) {
+			continue
+		}
+		if !meta.IsStatusConditionTrue(parent.Conditions, string(gatewayapi_v1.RouteConditionAccepted)) ||
+			!meta.IsStatusConditionTrue(parent.Conditions, string(gatewayapi_v1.RouteConditionResolvedRefs)) {
+			log.Debugf("Route not accepted by %s for parent %s", parent.ControllerName, ref.Name)
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// sameParentRef reports whether two parent references of a route in routeNamespace
+// refer to the same parent, taking the defaults of unset fields into account.
+func sameParentRef(a, b gatewayapi_v1.ParentReference, routeNamespace string) bool {
+	group := func(ref gatewayapi_v1.ParentReference) gatewayapi_v1.Group {
+		if ref.Group == nil {
+			return gatewayapi_v1.GroupName
+		}
+		return *ref.Group
+	}
+	kind := func(ref gatewayapi_v1.ParentReference) gatewayapi_v1.Kind {
+		if ref.Kind == nil {
+			return "Gateway"
+		}
+		return *ref.Kind
+	}
+	namespace := func(ref gatewayapi_v1.ParentReference) gatewayapi_v1.Namespace {
+		if ref.Namespace == nil {
+			return gatewayapi_v1.Namespace(routeNamespace)
+		}
+		return *ref.Namespace
+	}
+	sectionName := func(ref gatewayapi_v1.ParentReference) gatewayapi_v1.SectionName {
+		if ref.SectionName == nil {
+			return ""
+		}
+		return *ref.SectionName
+	}
+	port := func(ref gatewayapi_v1.ParentReference) gatewayapi_v1.PortNumber {
+		if ref.Port == nil {
+			return 0
+		}
+		return *ref.Port
+	}
+
+	return a.Name == b.Name && group(a) == group(b) && kind(a) == kind(b) && namespace(a) == namespace(b) &&
+		sectionName(a) == sectionName(b) && port(a) == port(b)
+}
+
+// gatewayProgrammed reports whether the Gateway's configuration has been applied to
+// its data plane, so that it can serve traffic.
+func gatewayProgrammed(gw *gatewayapi_v1.Gateway) bool {
+	return meta.IsStatusConditionTrue(gw.Status.Conditions, string(gatewayapi_v1.GatewayConditionProgrammed))
+}
+
+// programmedListeners returns the listeners the parent reports as programmed
+func programmedListeners(parent routeParent, listeners []gatewayapi_v1.Listener) (programmed []gatewayapi_v1.Listener) {
+	for _, listener := range listeners {
+		if slices.Contains(parent.listenersProgrammed, listener.Name) {
+			programmed = append(programmed, listener)
+		}
+	}
+	return programmed
+}
+
+// statusToCheck returns the status of a route lookupGateways verifies, nil if the
+// routeStatus mode is disabled.
+func statusToCheck(checkStatus bool, status *gatewayapi_v1.RouteStatus) *gatewayapi_v1.RouteStatus {
+	if !checkStatus {
+		return nil
+	}
+	return status
+}
@@ -0,0 +1,147 @@
+package gateway
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/coredns/caddy"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	gatewayapi_v1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestRouteStatusParsing(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		expected  bool
+	}{
+		{`k8s_gateway example.org`, false, false},
+		{`k8s_gateway example.org {
+	routeStatus
+}`, false, true},
+		{`k8s_gateway example.org {
+	routeStatus true
+}`, true, false},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		gw, err := parse(c)
+
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected error for input %s", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Unexpected error for input %s: %v", i, test.input, err)
+			continue
+		}
+		if gw.resourceFilters.routeStatus != test.expected {
+			t.Errorf("Test %d: Expected routeStatus %v, got %v", i, test.expected, gw.resourceFilters.routeStatus)
+		}
+	}
+}
+
+func TestLookupGatewaysRouteStatus(t *testing.T) {
+	condition := func(conditionType string, status metav1.ConditionStatus) metav1.Condition {
+		return metav1.Condition{Type: conditionType, Status: status}
+	}
+	accepted := []metav1.Condition{
+		condition(string(gatewayapi_v1.RouteConditionAccepted), metav1.ConditionTrue),
+		condition(string(gatewayapi_v1.RouteConditionResolvedRefs), metav1.ConditionTrue),
+	}
+	unresolved := []metav1.Condition{
+		condition(string(gatewayapi_v1.RouteConditionAccepted), metav1.ConditionTrue),
+		condition(string(gatewayapi_v1.RouteConditionResolvedRefs), metav1.ConditionFalse),
+	}
+	programmed := []metav1.Condition{condition(string(gatewayapi_v1.GatewayConditionProgrammed), metav1.ConditionTrue)}
+	sectionName := func(name gatewayapi_v1.SectionName) *gatewayapi_v1.SectionName { return &name }
+	namespace := func(ns gatewayapi_v1.Namespace) *gatewayapi_v1.Namespace { return &ns }
+
+	ipAddressType := gatewayapi_v1.IPAddressType
+	newGateway := func(name, addr string, conditions []metav1.Condition, listenerConditions []metav1.Condition) *gatewayapi_v1.Gateway {
+		return &gatewayapi_v1.Gateway{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns1"},
+			Spec: gatewayapi_v1.GatewaySpec{
+				Listeners: []gatewayapi_v1.Listener{{Name: "http", Protocol: gatewayapi_v1.HTTPProtocolType, Port: 80}},
+			},
+			Status: gatewayapi_v1.GatewayStatus{
+				Addresses:  []gatewayapi_v1.GatewayStatusAddress{{Type: &ipAddressType, Value: addr}},
+				Conditions: conditions,
+				Listeners:  []gatewayapi_v1.ListenerStatus{{Name: "http", Conditions: listenerConditions}},
+			},
+		}
+	}
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{gatewayUniqueIndex: gatewayIndexFunc})
+	for _, gw := range []*gatewayapi_v1.Gateway{
+		newGateway("ready", "192.0.2.1", programmed, []metav1.Condition{condition(string(gatewayapi_v1.ListenerConditionProgrammed), metav1.ConditionTrue)}),
+		newGateway("pending", "192.0.2.2", nil, []metav1.Condition{condition(string(gatewayapi_v1.ListenerConditionProgrammed), metav1.ConditionTrue)}),
+		newGateway("listener-pending", "192.0.2.3", programmed, []metav1.Condition{condition(string(gatewayapi_v1.ListenerConditionProgrammed), metav1.ConditionFalse)}),
+	} {
+		if err := indexer.Add(gw); err != nil {
+			t.Fatal(err)
+		}
+	}
+	parents := parentInformers{gateways: informers{&fakeSharedIndexInformer{indexer: indexer}}}
+
+	tests := []struct {
+		refs     []gatewayapi_v1.ParentReference
+		status   *gatewayapi_v1.RouteStatus
+		expected []string
+	}{
+		// status isn't checked
+		{[]gatewayapi_v1.ParentReference{{Name: "pending"}}, nil, []string{"192.0.2.2"}},
+		// accepted by a programmed gateway
+		{[]gatewayapi_v1.ParentReference{{Name: "ready"}}, &gatewayapi_v1.RouteStatus{Parents: []gatewayapi_v1.RouteParentStatus{
+			{ParentRef: gatewayapi_v1.ParentReference{Name: "ready", Namespace: namespace("ns1")}, Conditions: accepted},
+		}}, []string{"192.0.2.1"}},
+		// no status reported yet
+		{[]gatewayapi_v1.ParentReference{{Name: "ready"}}, &gatewayapi_v1.RouteStatus{}, nil},
+		// status of another listener
+		{[]gatewayapi_v1.ParentReference{{Name: "ready"}}, &gatewayapi_v1.RouteStatus{Parents: []gatewayapi_v1.RouteParentStatus{
+			{ParentRef: gatewayapi_v1.ParentReference{Name: "ready", SectionName: sectionName("http")}, Conditions: accepted},
+		}}, nil},
+		// backends not resolved
+		{[]gatewayapi_v1.ParentReference{{Name: "ready"}}, &gatewayapi_v1.RouteStatus{Parents: []gatewayapi_v1.RouteParentStatus{
+			{ParentRef: gatewayapi_v1.ParentReference{Name: "ready"}, Conditions: unresolved},
+		}}, nil},
+		// gateway not programmed
+		{[]gatewayapi_v1.ParentReference{{Name: "pending"}}, &gatewayapi_v1.RouteStatus{Parents: []gatewayapi_v1.RouteParentStatus{
+			{ParentRef: gatewayapi_v1.ParentReference{Name: "pending"}, Conditions: accepted},
+		}}, nil},
+		// listener not programmed
+		{[]gatewayapi_v1.ParentReference{{Name: "listener-pending"}}, &gatewayapi_v1.RouteStatus{Parents: []gatewayapi_v1.RouteParentStatus{
+			{ParentRef: gatewayapi_v1.ParentReference{Name: "listener-pending"}, Conditions: accepted},
+		}}, nil},
+		// every controller reporting on the parent must accept the route
+		{[]gatewayapi_v1.ParentReference{{Name: "ready"}}, &gatewayapi_v1.RouteStatus{Parents: []gatewayapi_v1.RouteParentStatus{
+			{ParentRef: gatewayapi_v1.ParentReference{Name: "ready"}, ControllerName: "example.com/old", Conditions: accepted},
+			{ParentRef: gatewayapi_v1.ParentReference{Name: "ready"}, ControllerName: "example.com/new", Conditions: unresolved},
+		}}, nil},
+		{[]gatewayapi_v1.ParentReference{{Name: "ready"}}, &gatewayapi_v1.RouteStatus{Parents: []gatewayapi_v1.RouteParentStatus{
+			{ParentRef: gatewayapi_v1.ParentReference{Name: "ready"}, ControllerName: "example.com/old", Conditions: accepted},
+			{ParentRef: gatewayapi_v1.ParentReference{Name: "ready"}, ControllerName: "example.com/new", Conditions: accepted},
+		}}, []string{"192.0.2.1"}},
+		// only the parent that accepted the route
+		{[]gatewayapi_v1.ParentReference{{Name: "ready"}, {Name: "pending"}}, &gatewayapi_v1.RouteStatus{Parents: []gatewayapi_v1.RouteParentStatus{
+			{ParentRef: gatewayapi_v1.ParentReference{Name: "ready"}, Conditions: accepted},
+			{ParentRef: gatewayapi_v1.ParentReference{Name: "pending"}, Conditions: unresolved},
+		}}, []string{"192.0.2.1"}},
+	}
+
+	for i, tc := range tests {
+		route := attachedRoute{namespace: "ns1", parentRefs: tc.refs, hostnames: []gatewayapi_v1.Hostname{"app.example.com"}, status: tc.status}
+		result := lookupGateways(parents, route, []string{"app.example.com"}, nil, httpRouteService)
+		var got []string
+		for _, addr := range result.addrs {
+			got = append(got, addr.String())
+		}
+		if !slices.Equal(got, tc.expected) {
+			t.Errorf("Test %d: expected addresses %v, got %v", i, tc.expected, got)
+		}
+	}
+}
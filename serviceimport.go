@@ -0,0 +1,200 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+
+	discovery "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	serviceImportHostnameIndex      = "serviceImportHostname"
+	serviceImportEndpointSliceIndex = "serviceImportEndpointSlice"
+	// mcsServiceNameLabel is set on the EndpointSlices of a headless ServiceImport,
+	// naming the ServiceImport in the same namespace
+	mcsServiceNameLabel = "multicluster.kubernetes.io/service-name"
+
+	serviceImportTypeClusterSetIP = "ClusterSetIP"
+	serviceImportTypeHeadless     = "Headless"
+)
+
+var mcsCRDClient rest.Interface
+
+var mcsGroupVersion = schema.GroupVersion{Group: "multicluster.x-k8s.io", Version: "v1alpha1"}
+
+// serviceImport holds the fields of a multicluster.x-k8s.io ServiceImport needed to
+// publish it, so that no MCS API dependency is pulled in.
+type serviceImport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              serviceImportSpec `json:"spec"`
+}
+
+type serviceImportSpec struct {
+	Type string   `json:"type"`
+	IPs  []string `json:"ips,omitempty"`
+}
+
+type serviceImportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []serviceImport `json:"items"`
+}
+
+func (in *serviceImport) DeepCopyObject() runtime.Object {
+	out := &serviceImport{TypeMeta: in.TypeMeta, Spec: in.Spec}
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec.IPs = slices.Clone(in.Spec.IPs)
+	return out
+}
+
+func (in *serviceImportList) DeepCopyObject() runtime.Object {
+	out := &serviceImportList{TypeMeta: in.TypeMeta}
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	for i := range in.Items {
+		out.Items = append(out.Items, *in.Items[i].DeepCopyObject().(*serviceImport))
+	}
+	return out
+}
+
+func addMCSTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypeWithName(mcsGroupVersion.WithKind("ServiceImport"), &serviceImport{})
+	scheme.AddKnownTypeWithName(mcsGroupVersion.WithKind("ServiceImportList"), &serviceImportList{})
+	metav1.AddToGroupVersion(scheme, mcsGroupVersion)
+	return nil
+}
+
+func newMCSRESTClient(config *rest.Config) (rest.Interface, error) {
+	return newCRDRESTClient(config, mcsGroupVersion, addMCSTypes)
+}
+
+// initializeServiceImportController watches ServiceImports, together with the
+// EndpointSlices of the headless ones
+func initializeServiceImportController(ctx context.Context, ctrl *KubeController, gw *Gateway) {
+	if !slices.Contains(dereferenceStrings(gw.ConfiguredResources), "ServiceImport") {
+		return
+	}
+	resource := gw.lookupResource("ServiceImport")
+	if resource == nil || mcsCRDClient == nil {
+		return
+	}
+	if !crdServesVersion(apiextensionsClient, "serviceimports.multicluster.x-k8s.io", mcsGroupVersion.Version) {
+		return
+	}
+
+	serviceImportControllers := ctrl.forEachNamespace(func(ns string) cache.SharedIndexInformer {
+		return cache.NewSharedIndexInformer(
+			&cache.ListWatch{
+				ListFunc:  serviceImportLister(ctx, ns),
+				WatchFunc: serviceImportWatcher(ctx, ns),
+			},
+			&serviceImport{},
+			defaultResyncPeriod,
+			cache.Indexers{serviceImportHostnameIndex: serviceImportHostnameIndexFunc},
+		)
+	})
+	endpointSliceControllers := ctrl.sharedEndpointSlices(ctx, cache.Indexers{
+		serviceImportEndpointSliceIndex: serviceImportEndpointSliceIndexFunc,
+	})
+	resource.lookup = lookupServiceImportIndex(serviceImportControllers, endpointSliceControllers)
+	resource.keys = listIndexKeys(serviceImportHostnameIndex, serviceImportControllers...)
+	ctrl.addController("ServiceImport", serviceImportControllers...)
+	log.Infof("ServiceImport controller initialized")
+}
+
+func serviceImportWatcher(ctx context.Context, ns string) func(metav1.ListOptions) (watch.Interface, error) {
+	return func(opts metav1.ListOptions) (watch.Interface, error) {
+		opts.Watch = true
+		return mcsCRDClient.Get().
+			Resource("serviceimports").
+			Namespace(ns).
+			VersionedParams(&opts, metav1.ParameterCodec).
+			Watch(ctx)
+	}
+}
+
+func serviceImportLister(ctx context.Context, ns string) func(metav1.ListOptions) (runtime.Object, error) {
+	return func(opts metav1.ListOptions) (runtime.Object, error) {
+		return mcsCRDClient.Get().
+			Resource("serviceimports").
+			Namespace(ns).
+			VersionedParams(&opts, metav1.ParameterCodec).
+			Do(ctx).
+			Get()
+	}
+}
+
+// serviceImportHostnameIndexFunc indexes a ServiceImport like a Service, by its
+// annotated hostnames or else by name.namespace
+func serviceImportHostnameIndexFunc(obj interface{}) ([]string, error) {
+	imported, ok := obj.(*serviceImport)
+	if !ok {
+		return []string{}, nil
+	}
+
+	if checkIgnoreLabel(imported.Labels) {
+		log.Debugf("Ignoring serviceImport %s due to %s label", imported.Name, ignoreLabelKey)
+		return []string{}, nil
+	}
+
+	if hostnames := annotatedHostnames(imported.ObjectMeta); len(hostnames) > 0 {
+		log.Debugf("Adding index %v for serviceImport %s", hostnames, imported.Name)
+		return hostnames, nil
+	}
+	return []string{imported.Name + "." + imported.Namespace}, nil
+}
+
+func serviceImportEndpointSliceIndexFunc(obj interface{}) ([]string, error) {
+	endpointSlice, ok := obj.(*discovery.EndpointSlice)
+	if !ok {
+		return []string{}, nil
+	}
+
+	serviceName, exists := endpointSlice.Labels[mcsServiceNameLabel]
+	if !exists {
+		return []string{}, nil
+	}
+	return []string{fmt.Sprintf("%s/%s", endpointSlice.Namespace, serviceName)}, nil
+}
+
+func lookupServiceImportIndex(ctrl, endpointSliceControllers informers) func([]string) lookupResult {
+	return func(indexKeys []string) (result lookupResult) {
+		var objs []interface{}
+		for _, key := range indexKeys {
+			objs = append(objs, ctrl.byIndex(serviceImportHostnameIndex, strings.ToLower(key))...)
+		}
+		log.Debugf("Found %d matching ServiceImport objects", len(objs))
+		for _, obj := range objs {
+			imported, _ := obj.(*serviceImport)
+			result.lowerTTL(annotationTTL(imported.ObjectMeta))
+			result.observe(imported)
+
+			switch imported.Spec.Type {
+			case serviceImportTypeClusterSetIP:
+				for _, ip := range imported.Spec.IPs {
+					addr, err := netip.ParseAddr(ip)
+					if err != nil {
+						continue
+					}
+					result.addrs = append(result.addrs, addr)
+				}
+			case serviceImportTypeHeadless:
+				endpointSliceKey := fmt.Sprintf("%s/%s", imported.Namespace, imported.Name)
+				endpointSliceObjs := endpointSliceControllers.byIndex(serviceImportEndpointSliceIndex, endpointSliceKey)
+				log.Debugf("Found %d EndpointSlices for serviceImport %s", len(endpointSliceObjs), endpointSliceKey)
+				result.observeAll(endpointSliceObjs)
+				result.addrs = append(result.addrs, readyEndpointAddresses(endpointSliceObjs)...)
+			}
+		}
+		return
+	}
+}
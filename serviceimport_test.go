@@ -0,0 +1,92 @@
+package gateway
+
+import (
+	"slices"
+	"testing"
+
+	discovery "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestLookupServiceImport(t *testing.T) {
+	importIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		serviceImportHostnameIndex: serviceImportHostnameIndexFunc,
+	})
+	endpointSliceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		serviceImportEndpointSliceIndex: serviceImportEndpointSliceIndexFunc,
+	})
+
+	for _, imported := range []*serviceImport{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "team-a"},
+			Spec:       serviceImportSpec{Type: serviceImportTypeClusterSetIP, IPs: []string{"192.0.2.60"}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "db",
+				Namespace:   "team-a",
+				Annotations: map[string]string{hostnameAnnotationKey: "db.example.com"},
+			},
+			Spec: serviceImportSpec{Type: serviceImportTypeHeadless},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "ignored", Namespace: "team-a", Labels: map[string]string{ignoreLabelKey: "true"}},
+			Spec:       serviceImportSpec{Type: serviceImportTypeClusterSetIP, IPs: []string{"192.0.2.61"}},
+		},
+	} {
+		if err := importIndexer.Add(imported); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ready, notReady := true, false
+	for _, endpointSlice := range []*discovery.EndpointSlice{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-cluster-1", Namespace: "team-a", Labels: map[string]string{mcsServiceNameLabel: "db"}},
+			Endpoints: []discovery.Endpoint{
+				{Addresses: []string{"10.1.0.1"}, Conditions: discovery.EndpointConditions{Ready: &ready}},
+				{Addresses: []string{"10.1.0.2"}, Conditions: discovery.EndpointConditions{Ready: &notReady}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-cluster-2", Namespace: "team-a", Labels: map[string]string{mcsServiceNameLabel: "db"}},
+			Endpoints:  []discovery.Endpoint{{Addresses: []string{"10.2.0.1"}}},
+		},
+		{
+			// the EndpointSlice of the local Service isn't part of the ServiceImport
+			ObjectMeta: metav1.ObjectMeta{Name: "db-local", Namespace: "team-a", Labels: map[string]string{discovery.LabelServiceName: "db"}},
+			Endpoints:  []discovery.Endpoint{{Addresses: []string{"10.0.0.1"}}},
+		},
+	} {
+		if err := endpointSliceIndexer.Add(endpointSlice); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lookup := lookupServiceImportIndex(
+		informers{&fakeSharedIndexInformer{indexer: importIndexer}},
+		informers{&fakeSharedIndexInformer{indexer: endpointSliceIndexer}},
+	)
+
+	tests := []struct {
+		indexKeys []string
+		expected  []string
+	}{
+		{[]string{"api.team-a.example.com", "api.team-a"}, []string{"192.0.2.60"}},
+		{[]string{"db.example.com", "db"}, []string{"10.1.0.1", "10.2.0.1"}},
+		{[]string{"db.team-a.example.com", "db.team-a"}, nil},
+		{[]string{"ignored.team-a.example.com", "ignored.team-a"}, nil},
+	}
+
+	for i, tc := range tests {
+		var got []string
+		for _, addr := range lookup(tc.indexKeys).addrs {
+			got = append(got, addr.String())
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tc.expected) {
+			t.Errorf("Test %d: expected addresses %v, got %v", i, tc.expected, got)
+		}
+	}
+}
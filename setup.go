@@ -2,13 +2,17 @@ package gateway
 
 import (
 	"context"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/transfer"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 var (
@@ -28,12 +32,29 @@ func setup(c *caddy.Controller) error {
 		return plugin.Error(thisPlugin, err)
 	}
 
-	err = gw.RunKubeController(context.Background())
+	// stops the informers and the zone watcher once this instance is shut down, e.g. on reload
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err = gw.RunKubeController(ctx)
 	if err != nil {
+		cancel()
 		return plugin.Error(thisPlugin, err)
 	}
 	gw.ExternalAddrFunc = gw.SelfAddress
 
+	// get the transfer plugin, so we can send notifies when a zone changes
+	c.OnStartup(func() error {
+		if t := dnsserver.GetConfig(c).Handler("transfer"); t != nil {
+			gw.Xfer = t.(*transfer.Transfer) // if found this must be OK.
+		}
+		go gw.watchZones(ctx)
+		return nil
+	})
+	c.OnShutdown(func() error {
+		cancel()
+		return nil
+	})
+
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
 		gw.Next = next
 		return gw
@@ -121,6 +142,18 @@ func parse(c *caddy.Controller) (*Gateway, error) {
 				}
 				gw.resourceFilters.gatewayClasses = args
 
+			case "traefikServices":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.Errf("traefikServices requires at least one argument (a namespace/name)")
+				}
+				for _, arg := range args {
+					if namespace, name, ok := strings.Cut(arg, "/"); !ok || namespace == "" || name == "" {
+						return nil, c.Errf("traefikServices must be in the form 'namespace/name', got: %s", arg)
+					}
+				}
+				gw.resourceFilters.traefikServices = append(gw.resourceFilters.traefikServices, args...)
+
 			case "serviceLabelSelectors":
 				args := c.RemainingArgs()
 				if len(args) == 0 {
@@ -137,6 +170,35 @@ func parse(c *caddy.Controller) (*Gateway, error) {
 					gw.resourceFilters.serviceLabelSelectors = append(gw.resourceFilters.serviceLabelSelectors, sel.String())
 				}
 
+			case "namespaces":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.Errf("namespaces requires at least one argument (a namespace)")
+				}
+				for _, arg := range args {
+					if errs := validation.IsDNS1123Label(arg); len(errs) > 0 {
+						return nil, c.Errf("invalid namespace %q: %s", arg, strings.Join(errs, ", "))
+					}
+				}
+				gw.resourceFilters.namespaces = append(gw.resourceFilters.namespaces, args...)
+
+			case "namespaceLabelSelector":
+				args := c.RemainingArgs()
+				if len(args) != 1 || args[0] == "" {
+					return nil, c.Errf("namespaceLabelSelector requires exactly one argument (a label selector string)")
+				}
+				sel, err := labels.Parse(args[0])
+				if err != nil {
+					return nil, c.Errf("invalid namespaceLabelSelector %q: %v", args[0], err)
+				}
+				gw.resourceFilters.namespaceLabelSelector = sel.String()
+
+			case "routeStatus":
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				gw.resourceFilters.routeStatus = true
+
 			case "nodeAddressType":
 				args := c.RemainingArgs()
 				if len(args) != 1 {
@@ -147,12 +209,64 @@ func parse(c *caddy.Controller) (*Gateway, error) {
 				}
 				gw.nodeAddressType = args[0]
 
+			case "hostnameAddresses":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				switch args[0] {
+				case "resolve":
+					gw.cnameHostnames = false
+				case "cname":
+					gw.cnameHostnames = true
+				default:
+					return nil, c.Errf("hostnameAddresses must be 'resolve' or 'cname', got: %s", args[0])
+				}
+
+			case "dnssec":
+				args := c.RemainingArgs()
+				if len(args) < 2 {
+					return nil, c.ArgErr()
+				}
+				if gw.signer == nil {
+					gw.signer = &dnssecSigner{}
+				}
+				switch args[0] {
+				case "file":
+					keys, err := parseKeyFiles(args[1:])
+					if err != nil {
+						return nil, c.Errf("failed to read DNSSEC keys: %v", err)
+					}
+					gw.signer.fileKeys = append(gw.signer.fileKeys, keys...)
+				case "secret":
+					if len(args) != 2 {
+						return nil, c.ArgErr()
+					}
+					if namespace, name, ok := strings.Cut(args[1], "/"); !ok || namespace == "" || name == "" {
+						return nil, c.Errf("dnssec secret must be in the form 'namespace/name', got: %s", args[1])
+					}
+					gw.signer.secret = args[1]
+				default:
+					return nil, c.Errf("dnssec keys must be loaded from a 'file' or a 'secret', got: %s", args[0])
+				}
+
 			default:
 				return nil, c.Errf("Unknown property '%s'", c.Val())
 			}
 		}
 	}
 
+	if gw.signer != nil {
+		if err := gw.signer.setKeys(gw, gw.signer.fileKeys); err != nil {
+			return nil, err
+		}
+	}
+
+	configuredResources := dereferenceStrings(gw.ConfiguredResources)
+	if (slices.Contains(configuredResources, "IngressRoute") || slices.Contains(configuredResources, "IngressRouteTCP")) && len(gw.resourceFilters.traefikServices) == 0 {
+		return nil, c.Errf("IngressRoute and IngressRouteTCP require the Traefik Services set by 'traefikServices'")
+	}
+
 	if len(gw.ConfiguredResources) == 0 {
 		log.Warningf("No resources specified in config. Using defaults: %s", DefaultResources)
 		gw.updateResources(DefaultResources)
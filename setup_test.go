@@ -149,3 +149,44 @@ func TestServiceLabelSelectorParsing(t *testing.T) {
 		}
 	}
 }
+
+func TestHostnameAddressesParsing(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		expected  bool
+	}{
+		{`k8s_gateway example.org`, false, false},
+		{`k8s_gateway example.org {
+	hostnameAddresses resolve
+}`, false, false},
+		{`k8s_gateway example.org {
+	hostnameAddresses cname
+}`, false, true},
+		{`k8s_gateway example.org {
+	hostnameAddresses alias
+}`, true, false},
+		{`k8s_gateway example.org {
+	hostnameAddresses
+}`, true, false},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		gw, err := parse(c)
+
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected error for input %s", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Unexpected error for input %s: %v", i, test.input, err)
+			continue
+		}
+		if gw.cnameHostnames != test.expected {
+			t.Errorf("Test %d: Expected cnameHostnames %v, got %v", i, test.expected, gw.cnameHostnames)
+		}
+	}
+}
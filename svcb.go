@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/miekg/dns"
+	gatewayapi_v1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const (
+	// http3OptionKey in the TLS options of an HTTPS listener advertises HTTP/3 on its port
+	http3OptionKey   = "k8s-gateway.dns/http3"
+	defaultHTTPSPort = 443
+)
+
+// httpsRecords returns an HTTPS record (RFC 9460) per port of the given listeners
+// of the Gateway that terminate or pass through TLS, see lookupResult.records. The
+// addresses of the Gateway are added as hints.
+func httpsRecords(listeners []gatewayapi_v1.Listener, service routeService, addrs []netip.Addr) (records []dns.RR) {
+	var ipv4Hint, ipv6Hint []net.IP
+	for _, addr := range addrs {
+		if addr.Is4() {
+			ipv4Hint = append(ipv4Hint, addr.AsSlice())
+		} else {
+			ipv6Hint = append(ipv6Hint, addr.AsSlice())
+		}
+	}
+
+	var ports []gatewayapi_v1.PortNumber
+	for _, listener := range listeners {
+		if slices.Contains(ports, listener.Port) {
+			continue
+		}
+
+		var alpn []string
+		switch listener.Protocol {
+		case gatewayapi_v1.HTTPSProtocolType:
+			alpn = service.alpn
+			if servesHTTP3(listeners, listener.Port) {
+				alpn = append([]string{"h3"}, alpn...)
+			}
+		case gatewayapi_v1.TLSProtocolType:
+		default:
+			continue
+		}
+		ports = append(ports, listener.Port)
+
+		// SvcParams must be in ascending order of their keys
+		var params []dns.SVCBKeyValue
+		if len(alpn) > 0 {
+			params = append(params, &dns.SVCBAlpn{Alpn: alpn})
+		}
+		if listener.Port != defaultHTTPSPort {
+			params = append(params, &dns.SVCBPort{Port: uint16(listener.Port)})
+		}
+		if len(ipv4Hint) > 0 {
+			params = append(params, &dns.SVCBIPv4Hint{Hint: ipv4Hint})
+		}
+		if len(ipv6Hint) > 0 {
+			params = append(params, &dns.SVCBIPv6Hint{Hint: ipv6Hint})
+		}
+
+		records = append(records, &dns.HTTPS{SVCB: dns.SVCB{
+			Hdr:      dns.RR_Header{Name: ".", Rrtype: dns.TypeHTTPS, Class: dns.ClassINET},
+			Priority: 1,
+			Target:   ".",
+			Value:    params,
+		}})
+	}
+	return records
+}
+
+// servesHTTP3 reports whether any of the HTTPS listeners on port enables HTTP/3 in
+// its TLS options
+func servesHTTP3(listeners []gatewayapi_v1.Listener, port gatewayapi_v1.PortNumber) bool {
+	for _, listener := range listeners {
+		if listener.Port != port || listener.Protocol != gatewayapi_v1.HTTPSProtocolType || listener.TLS == nil {
+			continue
+		}
+		if strings.EqualFold(string(listener.TLS.Options[http3OptionKey]), "true") {
+			return true
+		}
+	}
+	return false
+}
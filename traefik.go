@@ -0,0 +1,348 @@
+package gateway
+
+import (
+	"context"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	ingressRouteHostnameIndex    = "ingressRouteHostname"
+	ingressRouteTCPHostnameIndex = "ingressRouteTCPHostname"
+)
+
+var traefikCRDClient rest.Interface
+
+var traefikGroupVersion = schema.GroupVersion{Group: "traefik.io", Version: "v1alpha1"}
+
+// traefikHostMatcher finds the Host, HostHeader and HostSNI matchers of a Traefik
+// rule, together with their arguments. HostRegexp has no literal hosts to publish.
+var traefikHostMatcher = regexp.MustCompile("(!?)\\b(Host|HostHeader|HostSNI)\\(([^)]*)\\)")
+
+// traefikHostArgument finds the quoted arguments of a matcher, e.g. `a.example.com`
+var traefikHostArgument = regexp.MustCompile("`([^`]*)`|\"([^\"]*)\"")
+
+// traefikIngressRoute and traefikIngressRouteTCP hold the fields of the traefik.io
+// resources needed to publish them, so that no Traefik dependency is pulled in.
+type traefikIngressRoute struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              traefikRouteSpec `json:"spec"`
+}
+
+type traefikIngressRouteTCP struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              traefikRouteSpec `json:"spec"`
+}
+
+type traefikRouteSpec struct {
+	Routes []traefikRoute `json:"routes,omitempty"`
+}
+
+type traefikRoute struct {
+	Match string `json:"match"`
+}
+
+type traefikIngressRouteList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []traefikIngressRoute `json:"items"`
+}
+
+type traefikIngressRouteTCPList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []traefikIngressRouteTCP `json:"items"`
+}
+
+func (in *traefikIngressRoute) DeepCopyObject() runtime.Object {
+	out := &traefikIngressRoute{TypeMeta: in.TypeMeta}
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec.Routes = slices.Clone(in.Spec.Routes)
+	return out
+}
+
+func (in *traefikIngressRouteTCP) DeepCopyObject() runtime.Object {
+	out := &traefikIngressRouteTCP{TypeMeta: in.TypeMeta}
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec.Routes = slices.Clone(in.Spec.Routes)
+	return out
+}
+
+func (in *traefikIngressRouteList) DeepCopyObject() runtime.Object {
+	out := &traefikIngressRouteList{TypeMeta: in.TypeMeta}
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	for i := range in.Items {
+		out.Items = append(out.Items, *in.Items[i].DeepCopyObject().(*traefikIngressRoute))
+	}
+	return out
+}
+
+func (in *traefikIngressRouteTCPList) DeepCopyObject() runtime.Object {
+	out := &traefikIngressRouteTCPList{TypeMeta: in.TypeMeta}
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	for i := range in.Items {
+		out.Items = append(out.Items, *in.Items[i].DeepCopyObject().(*traefikIngressRouteTCP))
+	}
+	return out
+}
+
+func addTraefikTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypeWithName(traefikGroupVersion.WithKind("IngressRoute"), &traefikIngressRoute{})
+	scheme.AddKnownTypeWithName(traefikGroupVersion.WithKind("IngressRouteList"), &traefikIngressRouteList{})
+	scheme.AddKnownTypeWithName(traefikGroupVersion.WithKind("IngressRouteTCP"), &traefikIngressRouteTCP{})
+	scheme.AddKnownTypeWithName(traefikGroupVersion.WithKind("IngressRouteTCPList"), &traefikIngressRouteTCPList{})
+	metav1.AddToGroupVersion(scheme, traefikGroupVersion)
+	return nil
+}
+
+func newTraefikRESTClient(config *rest.Config) (rest.Interface, error) {
+	return newCRDRESTClient(config, traefikGroupVersion, addTraefikTypes)
+}
+
+// initializeTraefikController watches IngressRoutes and IngressRouteTCPs, together
+// with the Services of Traefik set by traefikServices. IngressRoutes report no
+// addresses of their own, they are published with the addresses of these Services.
+func initializeTraefikController(ctx context.Context, ctrl *KubeController, gw *Gateway) {
+	configuredResources := dereferenceStrings(gw.ConfiguredResources)
+	if !slices.Contains(configuredResources, "IngressRoute") && !slices.Contains(configuredResources, "IngressRouteTCP") {
+		return
+	}
+	if traefikCRDClient == nil {
+		return
+	}
+
+	// one informer per Traefik Service, limited to it by name, as Services needn't be
+	// watched otherwise
+	var serviceControllers informers
+	for _, service := range slices.Compact(slices.Sorted(slices.Values(gw.resourceFilters.traefikServices))) {
+		ns, name, _ := strings.Cut(service, "/")
+		serviceControllers = append(serviceControllers, cache.NewSharedIndexInformer(
+			&cache.ListWatch{
+				ListFunc:  namedServiceLister(ctx, ctrl.client, ns, name),
+				WatchFunc: namedServiceWatcher(ctx, ctrl.client, ns, name),
+			},
+			&core.Service{},
+			defaultResyncPeriod,
+			cache.Indexers{},
+		))
+	}
+	services := traefikServices{names: gw.resourceFilters.traefikServices, informers: serviceControllers}
+
+	var initialized bool
+	if slices.Contains(configuredResources, "IngressRoute") && crdServesVersion(apiextensionsClient, "ingressroutes.traefik.io", traefikGroupVersion.Version) {
+		if resource := gw.lookupResource("IngressRoute"); resource != nil {
+			ingressRouteControllers := ctrl.forEachNamespace(func(ns string) cache.SharedIndexInformer {
+				return cache.NewSharedIndexInformer(
+					&cache.ListWatch{
+						ListFunc:  traefikLister(ctx, "ingressroutes", ns),
+						WatchFunc: traefikWatcher(ctx, "ingressroutes", ns),
+					},
+					&traefikIngressRoute{},
+					defaultResyncPeriod,
+					cache.Indexers{ingressRouteHostnameIndex: ingressRouteHostnameIndexFunc},
+				)
+			})
+			resource.lookup = lookupTraefikRouteIndex(ingressRouteControllers, ingressRouteHostnameIndex, services)
+			resource.keys = listIndexKeys(ingressRouteHostnameIndex, ingressRouteControllers...)
+			ctrl.addController("IngressRoute", ingressRouteControllers...)
+			log.Infof("IngressRoute controller initialized")
+			initialized = true
+		}
+	}
+	if slices.Contains(configuredResources, "IngressRouteTCP") && crdServesVersion(apiextensionsClient, "ingressroutetcps.traefik.io", traefikGroupVersion.Version) {
+		if resource := gw.lookupResource("IngressRouteTCP"); resource != nil {
+			ingressRouteTCPControllers := ctrl.forEachNamespace(func(ns string) cache.SharedIndexInformer {
+				return cache.NewSharedIndexInformer(
+					&cache.ListWatch{
+						ListFunc:  traefikLister(ctx, "ingressroutetcps", ns),
+						WatchFunc: traefikWatcher(ctx, "ingressroutetcps", ns),
+					},
+					&traefikIngressRouteTCP{},
+					defaultResyncPeriod,
+					cache.Indexers{ingressRouteTCPHostnameIndex: ingressRouteTCPHostnameIndexFunc},
+				)
+			})
+			resource.lookup = lookupTraefikRouteIndex(ingressRouteTCPControllers, ingressRouteTCPHostnameIndex, services)
+			resource.keys = listIndexKeys(ingressRouteTCPHostnameIndex, ingressRouteTCPControllers...)
+			ctrl.addController("IngressRouteTCP", ingressRouteTCPControllers...)
+			log.Infof("IngressRouteTCP controller initialized")
+			initialized = true
+		}
+	}
+	if initialized {
+		// limited to the Traefik Services, so counted apart from the shared Service informers
+		ctrl.addController("TraefikService", serviceControllers...)
+	}
+}
+
+func namedServiceLister(ctx context.Context, c kubernetes.Interface, ns, name string) func(metav1.ListOptions) (runtime.Object, error) {
+	return func(opts metav1.ListOptions) (runtime.Object, error) {
+		opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		return c.CoreV1().Services(ns).List(ctx, opts)
+	}
+}
+
+func namedServiceWatcher(ctx context.Context, c kubernetes.Interface, ns, name string) func(metav1.ListOptions) (watch.Interface, error) {
+	return func(opts metav1.ListOptions) (watch.Interface, error) {
+		opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		return c.CoreV1().Services(ns).Watch(ctx, opts)
+	}
+}
+
+func traefikWatcher(ctx context.Context, resource, ns string) func(metav1.ListOptions) (watch.Interface, error) {
+	return func(opts metav1.ListOptions) (watch.Interface, error) {
+		opts.Watch = true
+		return traefikCRDClient.Get().
+			Resource(resource).
+			Namespace(ns).
+			VersionedParams(&opts, metav1.ParameterCodec).
+			Watch(ctx)
+	}
+}
+
+func traefikLister(ctx context.Context, resource, ns string) func(metav1.ListOptions) (runtime.Object, error) {
+	return func(opts metav1.ListOptions) (runtime.Object, error) {
+		return traefikCRDClient.Get().
+			Resource(resource).
+			Namespace(ns).
+			VersionedParams(&opts, metav1.ParameterCodec).
+			Do(ctx).
+			Get()
+	}
+}
+
+func ingressRouteHostnameIndexFunc(obj interface{}) ([]string, error) {
+	ingressRoute, ok := obj.(*traefikIngressRoute)
+	if !ok {
+		return []string{}, nil
+	}
+
+	if checkIgnoreLabel(ingressRoute.Labels) {
+		log.Debugf("Ignoring ingressRoute %s due to %s label", ingressRoute.Name, ignoreLabelKey)
+		return []string{}, nil
+	}
+
+	hostnames := traefikRouteHostnames(ingressRoute.Spec)
+	log.Debugf("Adding index %v for ingressRoute %s", hostnames, ingressRoute.Name)
+	return hostnames, nil
+}
+
+func ingressRouteTCPHostnameIndexFunc(obj interface{}) ([]string, error) {
+	ingressRouteTCP, ok := obj.(*traefikIngressRouteTCP)
+	if !ok {
+		return []string{}, nil
+	}
+
+	if checkIgnoreLabel(ingressRouteTCP.Labels) {
+		log.Debugf("Ignoring ingressRouteTCP %s due to %s label", ingressRouteTCP.Name, ignoreLabelKey)
+		return []string{}, nil
+	}
+
+	hostnames := traefikRouteHostnames(ingressRouteTCP.Spec)
+	log.Debugf("Adding index %v for ingressRouteTCP %s", hostnames, ingressRouteTCP.Name)
+	return hostnames, nil
+}
+
+// traefikRouteHostnames returns the hostnames matched by the rules of all routes
+func traefikRouteHostnames(spec traefikRouteSpec) (hostnames []string) {
+	for _, route := range spec.Routes {
+		for _, hostname := range parseTraefikRule(route.Match) {
+			if !slices.Contains(hostnames, hostname) {
+				hostnames = append(hostnames, hostname)
+			}
+		}
+	}
+	return hostnames
+}
+
+// parseTraefikRule returns the literal hosts of the Host, HostHeader and HostSNI
+// matchers of a rule, e.g. a.example.com and b.example.com for
+// "Host(`a.example.com`) || HostSNI(`b.example.com`)". Negated matchers and the
+// catch-all HostSNI(`*`) are skipped.
+func parseTraefikRule(rule string) (hostnames []string) {
+	for _, matcher := range traefikHostMatcher.FindAllStringSubmatch(rule, -1) {
+		if matcher[1] == "!" {
+			continue
+		}
+		for _, argument := range traefikHostArgument.FindAllStringSubmatch(matcher[3], -1) {
+			hostname := strings.ToLower(argument[1] + argument[2])
+			if hostname == "*" || !checkDomainValid(hostname) {
+				continue
+			}
+			hostnames = append(hostnames, hostname)
+		}
+	}
+	return hostnames
+}
+
+// traefikServices are the Services exposing Traefik, by namespace/name
+type traefikServices struct {
+	names     []string
+	informers informers
+}
+
+// addresses returns the addresses of the Traefik Services, from their external IPs
+// or their load balancer status
+func (s traefikServices) addresses() (result lookupResult) {
+	for _, name := range s.names {
+		for _, informer := range s.informers {
+			obj, exists, _ := informer.GetIndexer().GetByKey(name)
+			if !exists {
+				continue
+			}
+			service, _ := obj.(*core.Service)
+			result.observe(service)
+			if len(service.Spec.ExternalIPs) > 0 {
+				for _, ip := range service.Spec.ExternalIPs {
+					if addr, err := netip.ParseAddr(ip); err == nil {
+						result.addrs = append(result.addrs, addr)
+					}
+				}
+				continue
+			}
+			addrs, names := fetchServiceLoadBalancerIPs(service.Status.LoadBalancer.Ingress)
+			result.addrs = append(result.addrs, addrs...)
+			result.hostnames = append(result.hostnames, names...)
+		}
+	}
+	return
+}
+
+func lookupTraefikRouteIndex(ctrl informers, index string, services traefikServices) func([]string) lookupResult {
+	return func(indexKeys []string) (result lookupResult) {
+		var objs []interface{}
+		for _, key := range indexKeys {
+			objs = append(objs, ctrl.byIndex(index, strings.ToLower(key))...)
+		}
+		log.Debugf("Found %d matching Traefik route objects", len(objs))
+		if len(objs) == 0 {
+			return
+		}
+
+		for _, obj := range objs {
+			switch route := obj.(type) {
+			case *traefikIngressRoute:
+				result.lowerTTL(annotationTTL(route.ObjectMeta))
+				result.observe(route)
+			case *traefikIngressRouteTCP:
+				result.lowerTTL(annotationTTL(route.ObjectMeta))
+				result.observe(route)
+			}
+		}
+		return result.merge(services.addresses())
+	}
+}
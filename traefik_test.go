@@ -0,0 +1,143 @@
+package gateway
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/coredns/caddy"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestParseTraefikRule(t *testing.T) {
+	tests := []struct {
+		rule     string
+		expected []string
+	}{
+		{"Host(`a.example.com`)", []string{"a.example.com"}},
+		{"Host(`a.example.com`) || HostSNI(`b.example.com`)", []string{"a.example.com", "b.example.com"}},
+		{"Host(`A.example.com`, `c.example.com`) && PathPrefix(`/api`)", []string{"a.example.com", "c.example.com"}},
+		{`Host("d.example.com")`, []string{"d.example.com"}},
+		{"HostHeader(`e.example.com`)", []string{"e.example.com"}},
+		{"HostRegexp(`^.+\\.example\\.com$`)", nil},
+		{"!Host(`f.example.com`) && Host(`g.example.com`)", []string{"g.example.com"}},
+		{"HostSNI(`*`)", nil},
+		{"PathPrefix(`/`)", nil},
+	}
+
+	for i, tc := range tests {
+		if got := parseTraefikRule(tc.rule); !slices.Equal(got, tc.expected) {
+			t.Errorf("Test %d: expected hosts %v for %s, got %v", i, tc.expected, tc.rule, got)
+		}
+	}
+}
+
+func TestTraefikServicesParsing(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		expected  []string
+	}{
+		{`k8s_gateway example.org {
+	resources IngressRoute
+	traefikServices traefik/traefik
+}`, false, []string{"traefik/traefik"}},
+		{`k8s_gateway example.org {
+	traefikServices traefik/traefik
+	traefikServices traefik/traefik-internal
+}`, false, []string{"traefik/traefik", "traefik/traefik-internal"}},
+		{`k8s_gateway example.org {
+	resources IngressRouteTCP
+}`, true, nil},
+		{`k8s_gateway example.org {
+	traefikServices traefik
+}`, true, nil},
+		{`k8s_gateway example.org {
+	traefikServices
+}`, true, nil},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		gw, err := parse(c)
+
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected error for input %s", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Unexpected error for input %s: %v", i, test.input, err)
+			continue
+		}
+		if !slices.Equal(gw.resourceFilters.traefikServices, test.expected) {
+			t.Errorf("Test %d: Expected traefikServices %v, got %v", i, test.expected, gw.resourceFilters.traefikServices)
+		}
+	}
+}
+
+func TestLookupTraefikRoute(t *testing.T) {
+	routeIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		ingressRouteHostnameIndex: ingressRouteHostnameIndexFunc,
+	})
+	serviceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+
+	for _, route := range []*traefikIngressRoute{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-a"},
+			Spec: traefikRouteSpec{Routes: []traefikRoute{
+				{Match: "Host(`app.example.com`) && PathPrefix(`/`)"},
+				{Match: "Host(`api.example.com`)"},
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "ignored", Namespace: "team-a", Labels: map[string]string{ignoreLabelKey: "true"}},
+			Spec:       traefikRouteSpec{Routes: []traefikRoute{{Match: "Host(`ignored.example.com`)"}}},
+		},
+	} {
+		if err := routeIndexer.Add(route); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, svc := range []*core.Service{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "traefik", Namespace: "traefik"},
+			Spec:       core.ServiceSpec{Type: core.ServiceTypeLoadBalancer},
+			Status:     core.ServiceStatus{LoadBalancer: core.LoadBalancerStatus{Ingress: []core.LoadBalancerIngress{{IP: "192.0.2.40"}}}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "traefik"},
+			Spec:       core.ServiceSpec{Type: core.ServiceTypeLoadBalancer},
+			Status:     core.ServiceStatus{LoadBalancer: core.LoadBalancerStatus{Ingress: []core.LoadBalancerIngress{{IP: "192.0.2.41"}}}},
+		},
+	} {
+		if err := serviceIndexer.Add(svc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	services := traefikServices{names: []string{"traefik/traefik"}, informers: informers{&fakeSharedIndexInformer{indexer: serviceIndexer}}}
+	lookup := lookupTraefikRouteIndex(informers{&fakeSharedIndexInformer{indexer: routeIndexer}}, ingressRouteHostnameIndex, services)
+
+	tests := []struct {
+		indexKeys []string
+		expected  []string
+	}{
+		{[]string{"app.example.com"}, []string{"192.0.2.40"}},
+		{[]string{"api.example.com"}, []string{"192.0.2.40"}},
+		{[]string{"ignored.example.com"}, nil},
+		{[]string{"unknown.example.com"}, nil},
+	}
+
+	for i, tc := range tests {
+		var got []string
+		for _, addr := range lookup(tc.indexKeys).addrs {
+			got = append(got, addr.String())
+		}
+		if !slices.Equal(got, tc.expected) {
+			t.Errorf("Test %d: expected addresses %v, got %v", i, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,402 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/transfer"
+	"github.com/coredns/coredns/request"
+	"github.com/miekg/dns"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// notifyDelay batches the informer events of a single change into one zone refresh
+	notifyDelay = time.Second
+	// maxZoneVersions is the number of zone versions kept to answer IXFR requests
+	maxZoneVersions = 16
+)
+
+// zoneVersion is the content of a zone at a given serial, without its SOA record
+type zoneVersion struct {
+	serial  uint32
+	records []dns.RR
+}
+
+// zoneHistory keeps the most recent versions of every zone, oldest first.
+type zoneHistory struct {
+	mu       sync.Mutex
+	versions map[string][]zoneVersion
+}
+
+func newZoneHistory() *zoneHistory {
+	return &zoneHistory{versions: make(map[string][]zoneVersion)}
+}
+
+// current returns the latest version of zone
+func (zh *zoneHistory) current(zone string) (zoneVersion, bool) {
+	zh.mu.Lock()
+	defer zh.mu.Unlock()
+
+	versions := zh.versions[zone]
+	if len(versions) == 0 {
+		return zoneVersion{}, false
+	}
+	return versions[len(versions)-1], true
+}
+
+// since returns the latest version of zone and the version at serial, if that is still known
+func (zh *zoneHistory) since(zone string, serial uint32) (current, old zoneVersion, ok bool) {
+	zh.mu.Lock()
+	defer zh.mu.Unlock()
+
+	versions := zh.versions[zone]
+	if len(versions) == 0 {
+		return zoneVersion{}, zoneVersion{}, false
+	}
+	current = versions[len(versions)-1]
+	for _, v := range versions {
+		if v.serial == serial {
+			return current, v, true
+		}
+	}
+	return current, zoneVersion{}, false
+}
+
+// update records the records as the latest version of zone and reports whether
+// they differ from the previous version. revision is the highest resourceVersion
+// of the objects the records were computed from, see zoneSerial.
+func (zh *zoneHistory) update(zone string, records []dns.RR, revision uint64) bool {
+	zh.mu.Lock()
+	defer zh.mu.Unlock()
+
+	versions := zh.versions[zone]
+	var previous uint32
+	if len(versions) > 0 {
+		if equalRecords(versions[len(versions)-1].records, records) {
+			return false
+		}
+		previous = versions[len(versions)-1].serial
+	}
+
+	versions = append(versions, zoneVersion{serial: zoneSerial(previous, revision), records: records})
+	if len(versions) > maxZoneVersions {
+		versions = versions[len(versions)-maxZoneVersions:]
+	}
+	zh.versions[zone] = versions
+	return true
+}
+
+// eventHandler returns an informer event handler that is called on any change of a watched object
+func (gw *Gateway) eventHandler() cache.ResourceEventHandler {
+	changed := func() {
+		gw.reverse.invalidate()
+		select {
+		case gw.changes <- struct{}{}:
+		default:
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { changed() },
+		UpdateFunc: func(interface{}, interface{}) { changed() },
+		DeleteFunc: func(interface{}) { changed() },
+	}
+}
+
+// watchZones refreshes the zones after informer events and sends NOTIFY messages to
+// the secondaries of every zone that changed.
+func (gw *Gateway) watchZones(ctx context.Context) {
+	// compute the zones once synced, even if no object is ever added
+	select {
+	case gw.changes <- struct{}{}:
+	default:
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-gw.changes:
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(notifyDelay):
+		}
+
+		gw.Controller.updateObjectCounts()
+
+		if !gw.Controller.HasSynced() {
+			// try again once all resources are synced
+			select {
+			case gw.changes <- struct{}{}:
+			default:
+			}
+			continue
+		}
+
+		for _, zone := range gw.refreshZones() {
+			if err := gw.Xfer.Notify(zone); err != nil {
+				log.Warningf("failed to send notify for zone %s: %s", zone, err)
+			}
+		}
+	}
+}
+
+// refreshZones recomputes all zones and returns the ones that changed
+func (gw *Gateway) refreshZones() (changed []string) {
+	for _, zone := range gw.Zones {
+		records, revision := gw.zoneRecords(zone)
+		if gw.history.update(zone, records, revision) {
+			changed = append(changed, zone)
+		}
+	}
+	if len(changed) > 0 {
+		log.Debugf("zones %v changed", changed)
+	}
+	return changed
+}
+
+// zoneVersion returns the latest version of zone, computing it if it's not known yet
+func (gw *Gateway) zoneVersion(zone string) zoneVersion {
+	if v, ok := gw.history.current(zone); ok {
+		return v
+	}
+	records, revision := gw.zoneRecords(zone)
+	gw.history.update(zone, records, revision)
+	v, _ := gw.history.current(zone)
+	return v
+}
+
+// zoneSerialOf returns the serial of the latest version of zone computed by
+// watchZones, without computing the zone. It is 0 until the zone is first computed.
+func (gw *Gateway) zoneSerialOf(zone string) uint32 {
+	v, _ := gw.history.current(zone)
+	return v.serial
+}
+
+// Transfer implements the transfer.Transferer interface.
+func (gw *Gateway) Transfer(zone string, serial uint32) (<-chan []dns.RR, error) {
+	zone = strings.ToLower(zone)
+	if !slices.Contains(gw.Zones, zone) {
+		return nil, transfer.ErrNotAuthoritative
+	}
+	if !gw.Controller.HasSynced() {
+		return nil, plugin.Error(thisPlugin, fmt.Errorf("could not sync required resources"))
+	}
+
+	gw.zoneVersion(zone)
+	current, old, ok := gw.history.since(zone, serial)
+	soa := gw.soa(request.Request{Zone: zone})
+	soa.Serial = current.serial
+
+	ch := make(chan []dns.RR)
+	go func() {
+		defer close(ch)
+
+		switch {
+		case serial != 0 && !serialLess(serial, current.serial):
+			// IXFR from a serial that is up to date, or ahead of this replica
+			ch <- []dns.RR{soa}
+		case serial != 0 && ok:
+			// IXFR as a single condensed difference sequence
+			oldSOA := dns.Copy(soa).(*dns.SOA)
+			oldSOA.Serial = old.serial
+			deleted, added := diffRecords(old.records, current.records)
+
+			ch <- append([]dns.RR{soa, oldSOA}, deleted...)
+			ch <- append([]dns.RR{soa}, added...)
+			ch <- []dns.RR{soa}
+		default:
+			// AXFR, also used for IXFR from a serial that is no longer known
+			ch <- append([]dns.RR{soa}, current.records...)
+			ch <- []dns.RR{soa}
+		}
+	}()
+	return ch, nil
+}
+
+// zoneRecords computes all records of zone from the configured resources, sorted
+// canonically, and the highest resourceVersion of the objects they were computed
+// from. The SOA record is not included.
+func (gw *Gateway) zoneRecords(zone string) (records []dns.RR, revision uint64) {
+	m := new(dns.Msg)
+	m.SetQuestion(zone, dns.TypeNS)
+	state := request.Request{Req: m, Zone: zone}
+
+	records = append(records, gw.nameservers(state)...)
+	for _, resource := range gw.Resources {
+		// the objects publishing the glue records
+		revision = max(revision, resource.lookup([]string{gw.apex}).revision, resource.lookup([]string{gw.secondNS}).revision)
+	}
+	if gw.ExternalAddrFunc != nil {
+		for _, rr := range gw.ExternalAddrFunc(state) {
+			rr.Header().Ttl = gw.ttlSOA
+			records = append(records, rr)
+		}
+	}
+
+	if isReverseZone(zone) {
+		for addr, entry := range gw.reverse.all(gw) {
+			name := reverseName(addr)
+			if dns.IsSubDomain(zone, name) {
+				records = append(records, gw.PTR(name, entry.ttl, entry.names)...)
+				revision = max(revision, entry.revision)
+			}
+		}
+		return sortRecords(records), revision
+	}
+
+	seen := make(map[string]struct{})
+	forwardZones := gw.forwardZones()
+	for _, resource := range gw.Resources {
+		for _, key := range resource.keys() {
+			if key == "" {
+				continue
+			}
+			for _, name := range indexKeyNames(key, forwardZones) {
+				if _, dup := seen[name]; dup || !dns.IsSubDomain(zone, name) {
+					continue
+				}
+				seen[name] = struct{}{}
+				nameRecords, nameRevision := gw.nameRecords(name, zone)
+				records = append(records, nameRecords...)
+				revision = max(revision, nameRevision)
+			}
+		}
+	}
+	return sortRecords(records), revision
+}
+
+// nameRecords returns all records that queries for name are answered with, and
+// the highest resourceVersion of the objects publishing them. Load balancer
+// hostnames are not resolved on every zone refresh, but published as a CNAME,
+// unless the name holds addresses of its own.
+func (gw *Gateway) nameRecords(name, zone string) (records []dns.RR, revision uint64) {
+	indexKeySets := [][]string{gw.getQueryIndexKeys(name, zone)}
+
+	result := gw.matchingResult(indexKeySets, func(result lookupResult) lookupResult { return result }, nil)
+	ttl := gw.recordTTL(result)
+	if len(result.hostnames) > 0 && len(result.addrs) == 0 {
+		return gw.CNAME(name, ttl, result.hostnames), result.revision
+	}
+
+	var ipv4Addrs, ipv6Addrs []netip.Addr
+	for _, addr := range result.addrs {
+		if addr.Is4() {
+			ipv4Addrs = append(ipv4Addrs, addr)
+		}
+		if addr.Is6() {
+			ipv6Addrs = append(ipv6Addrs, addr)
+		}
+	}
+	records = append(records, gw.A(name, ttl, ipv4Addrs)...)
+	records = append(records, gw.AAAA(name, ttl, ipv6Addrs)...)
+	records = append(records, gw.TXT(name, ttl, result.raws)...)
+	records = append(records, gw.records(name, ttl, result.records)...)
+
+	ports := gw.publishedPorts(indexKeySets)
+	seen := make(map[servicePort]struct{})
+	for _, port := range ports.ports {
+		if _, dup := seen[port]; dup {
+			continue
+		}
+		seen[port] = struct{}{}
+		srvName := "_" + port.name + "._" + strings.ToLower(port.protocol) + "." + name
+		records = append(records, gw.SRV(srvName, name, ttl, []servicePort{port})...)
+	}
+	return records, max(result.revision, ports.revision)
+}
+
+// publishedPorts returns the result of the first resource that publishes any
+// ports for the given index keys, see getMatchingPorts.
+func (gw *Gateway) publishedPorts(indexKeySets [][]string) lookupResult {
+	for _, indexKeys := range indexKeySets {
+		for _, resource := range gw.Resources {
+			result := resource.lookup(indexKeys)
+			if !result.empty() && len(result.ports) > 0 {
+				return result
+			}
+		}
+	}
+	return lookupResult{}
+}
+
+// reverseName returns the name of addr in the in-addr.arpa. or ip6.arpa. zone
+func reverseName(addr netip.Addr) string {
+	name, _ := dns.ReverseAddr(addr.String())
+	return name
+}
+
+// sortRecords sorts records by name, type and data and removes duplicates, so
+// that versions of a zone can be compared
+func sortRecords(records []dns.RR) []dns.RR {
+	slices.SortFunc(records, func(a, b dns.RR) int {
+		if c := strings.Compare(strings.ToLower(a.Header().Name), strings.ToLower(b.Header().Name)); c != 0 {
+			return c
+		}
+		if a.Header().Rrtype != b.Header().Rrtype {
+			return int(a.Header().Rrtype) - int(b.Header().Rrtype)
+		}
+		return strings.Compare(a.String(), b.String())
+	})
+	return slices.CompactFunc(records, func(a, b dns.RR) bool { return a.String() == b.String() })
+}
+
+// equalRecords reports whether two sorted record sets are the same
+func equalRecords(a, b []dns.RR) bool {
+	return slices.EqualFunc(a, b, func(x, y dns.RR) bool { return x.String() == y.String() })
+}
+
+// diffRecords returns the records that are only in old and the ones only in current
+func diffRecords(old, current []dns.RR) (deleted, added []dns.RR) {
+	oldSet := make(map[string]struct{}, len(old))
+	for _, rr := range old {
+		oldSet[rr.String()] = struct{}{}
+	}
+	currentSet := make(map[string]struct{}, len(current))
+	for _, rr := range current {
+		currentSet[rr.String()] = struct{}{}
+		if _, ok := oldSet[rr.String()]; !ok {
+			added = append(added, rr)
+		}
+	}
+	for _, rr := range old {
+		if _, ok := currentSet[rr.String()]; !ok {
+			deleted = append(deleted, rr)
+		}
+	}
+	return deleted, added
+}
+
+// zoneSerial returns the SOA serial of a new zone version. It is the highest
+// resourceVersion of the objects the zone's records were computed from, so that it
+// only depends on the zone's content: replicas serving the same objects agree on
+// it and restarted replicas continue where they left off. Kubernetes assigns every
+// write a greater resourceVersion, so that any object added or changed moves the
+// serial forward. The serial is truncated to 32 bits and compared with RFC 1982
+// serial arithmetic. If the revision isn't ahead of the previous serial, i.e. when
+// objects were only removed, the previous serial is incremented instead, so that
+// the serial always increases.
+func zoneSerial(previous uint32, revision uint64) uint32 {
+	serial := uint32(revision)
+	if previous != 0 && !serialLess(previous, serial) {
+		serial = previous + 1
+	}
+	// a serial of 0 requests an AXFR, so it's never used
+	if serial == 0 {
+		serial = 1
+	}
+	return serial
+}
+
+// serialLess reports whether serial a is lower than b according to RFC 1982
+func serialLess(a, b uint32) bool {
+	return a != b && int32(b-a) > 0
+}
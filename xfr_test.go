@@ -0,0 +1,207 @@
+package gateway
+
+import (
+	"maps"
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/plugin/transfer"
+	"github.com/coredns/coredns/request"
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// newTransferGateway returns a Gateway whose Services publish addrs by index key,
+// as objects of the given resourceVersions
+func newTransferGateway(addrs map[string][]netip.Addr, versions map[string]uint64) *Gateway {
+	gw := newGateway()
+	gw.Zones = []string{"example.com.", "192.in-addr.arpa."}
+	gw.Next = test.NextHandler(dns.RcodeSuccess, nil)
+	gw.ExternalAddrFunc = gw.SelfAddress
+	gw.Controller = &KubeController{hasSynced: true}
+
+	service := gw.lookupResource("Service")
+	service.lookup = func(keys []string) (result lookupResult) {
+		for _, key := range keys {
+			result.addrs = append(result.addrs, addrs[key]...)
+			if len(addrs[key]) > 0 {
+				result.revision = max(result.revision, versions[key])
+			}
+		}
+		if len(result.addrs) > 0 {
+			result.ports = []servicePort{{name: "http", protocol: "TCP", port: 80}}
+		}
+		return result
+	}
+	service.keys = func() []string { return slices.Collect(maps.Keys(addrs)) }
+	return gw
+}
+
+func collectTransfer(t *testing.T, gw *Gateway, zone string, serial uint32) (records []string) {
+	ch, err := gw.Transfer(zone, serial)
+	if err != nil {
+		t.Fatalf("unexpected transfer error: %v", err)
+	}
+	for rrs := range ch {
+		for _, rr := range rrs {
+			records = append(records, rr.String())
+		}
+	}
+	return records
+}
+
+func TestTransferAXFR(t *testing.T) {
+	gw := newTransferGateway(map[string][]netip.Addr{
+		"svc1.ns1":               {netip.MustParseAddr("192.0.2.1"), netip.MustParseAddr("2001:db8::1")},
+		"*.wildcard.example.com": {netip.MustParseAddr("192.0.2.2")},
+		"dns1.kube-system":       {netip.MustParseAddr("192.0.2.53")},
+	}, nil)
+	if soa := gw.soa(request.Request{Zone: "example.com."}); soa.Serial != 0 {
+		t.Errorf("expected no serial before the zone is computed, got %d", soa.Serial)
+	}
+	gw.refreshZones()
+	soa := gw.soa(request.Request{Zone: "example.com."}).String()
+
+	want := []string{
+		soa,
+		"example.com.\t60\tIN\tNS\tdns1.kube-system.example.com.",
+		"*.wildcard.example.com.\t60\tIN\tA\t192.0.2.2",
+		"_http._tcp.*.wildcard.example.com.\t60\tIN\tSRV\t0 100 80 *.wildcard.example.com.",
+		"_http._tcp.dns1.kube-system.example.com.\t60\tIN\tSRV\t0 100 80 dns1.kube-system.example.com.",
+		"_http._tcp.svc1.ns1.example.com.\t60\tIN\tSRV\t0 100 80 svc1.ns1.example.com.",
+		"dns1.kube-system.example.com.\t60\tIN\tA\t192.0.2.53",
+		"svc1.ns1.example.com.\t60\tIN\tA\t192.0.2.1",
+		"svc1.ns1.example.com.\t60\tIN\tAAAA\t2001:db8::1",
+		soa,
+	}
+	got := collectTransfer(t, gw, "example.com.", 0)
+	slices.Sort(want[1 : len(want)-1])
+	slices.Sort(got[1 : len(got)-1])
+	if !slices.Equal(got, want) {
+		t.Errorf("unexpected AXFR\n got: %q\nwant: %q", got, want)
+	}
+
+	reverse := collectTransfer(t, gw, "192.in-addr.arpa.", 0)
+	if !slices.Contains(reverse, "1.2.0.192.in-addr.arpa.\t60\tIN\tPTR\tsvc1.ns1.example.com.") {
+		t.Errorf("expected PTR record for 192.0.2.1 in reverse zone transfer, got %q", reverse)
+	}
+
+	if _, err := gw.Transfer("example.org.", 0); err != transfer.ErrNotAuthoritative {
+		t.Errorf("expected ErrNotAuthoritative for unknown zone, got %v", err)
+	}
+}
+
+func TestTransferIXFR(t *testing.T) {
+	addrs := map[string][]netip.Addr{
+		"svc1.ns1": {netip.MustParseAddr("192.0.2.1")},
+		"svc2.ns1": {netip.MustParseAddr("192.0.2.2")},
+	}
+	versions := map[string]uint64{"svc1.ns1": 100, "svc2.ns1": 90}
+	gw := newTransferGateway(addrs, versions)
+	if changed := gw.refreshZones(); len(changed) != len(gw.Zones) {
+		t.Errorf("expected the first version of all zones to be reported as changed, got %v", changed)
+	}
+	oldSerial := gw.zoneVersion("example.com.").serial
+
+	if changed := gw.refreshZones(); len(changed) != 0 {
+		t.Errorf("expected no changed zones, got %v", changed)
+	}
+
+	addrs["svc2.ns1"] = []netip.Addr{netip.MustParseAddr("192.0.2.3")}
+	versions["svc2.ns1"] = 105
+	if changed := gw.refreshZones(); !slices.Contains(changed, "example.com.") {
+		t.Fatalf("expected example.com. to have changed, got %v", changed)
+	}
+	newSerial := gw.zoneVersion("example.com.").serial
+	if oldSerial != 100 || newSerial != 105 {
+		t.Fatalf("expected the serial to follow the resourceVersion from 100 to 105, got %d and %d", oldSerial, newSerial)
+	}
+
+	ixfr := collectTransfer(t, gw, "example.com.", oldSerial)
+	if len(ixfr) != 6 {
+		t.Fatalf("expected 6 records in IXFR, got %q", ixfr)
+	}
+	old := ixfr[1]
+	if ixfr[0] != ixfr[3] || ixfr[0] != ixfr[5] || old == ixfr[0] {
+		t.Errorf("unexpected SOA records in IXFR: %q", ixfr)
+	}
+	if ixfr[2] != "svc2.ns1.example.com.\t60\tIN\tA\t192.0.2.2" {
+		t.Errorf("expected deleted record for 192.0.2.2, got %q", ixfr[2])
+	}
+	if ixfr[4] != "svc2.ns1.example.com.\t60\tIN\tA\t192.0.2.3" {
+		t.Errorf("expected added record for 192.0.2.3, got %q", ixfr[4])
+	}
+
+	if upToDate := collectTransfer(t, gw, "example.com.", newSerial); len(upToDate) != 1 {
+		t.Errorf("expected a single SOA for an up to date IXFR, got %q", upToDate)
+	}
+
+	// a secondary ahead of this replica is up to date as well
+	if ahead := collectTransfer(t, gw, "example.com.", newSerial+1); len(ahead) != 1 {
+		t.Errorf("expected a single SOA for an IXFR from a greater serial, got %q", ahead)
+	}
+
+	// older serials that are no longer known fall back to AXFR
+	if axfr := collectTransfer(t, gw, "example.com.", 1); len(axfr) != 7 {
+		t.Errorf("expected an AXFR of 7 records, got %q", axfr)
+	}
+}
+
+func TestTransferHostnames(t *testing.T) {
+	gw := newTransferGateway(map[string][]netip.Addr{"svc1.ns1": {netip.MustParseAddr("192.0.2.1")}}, nil)
+	ingress := gw.lookupResource("Ingress")
+	ingress.lookup = func(keys []string) (result lookupResult) {
+		if slices.Contains(keys, "lb.example.com") {
+			result.hostnames = []string{"lb.invalid"}
+		}
+		return result
+	}
+	ingress.keys = func() []string { return []string{"lb.example.com"} }
+
+	failures := testutil.ToFloat64(hostnameLookupFailures)
+	axfr := collectTransfer(t, gw, "example.com.", 0)
+	if !slices.Contains(axfr, "lb.example.com.\t60\tIN\tCNAME\tlb.invalid.") {
+		t.Errorf("expected load balancer hostname to be published as a CNAME, got %q", axfr)
+	}
+	if got := testutil.ToFloat64(hostnameLookupFailures) - failures; got != 0 {
+		t.Errorf("expected zone builds not to resolve hostnames, got %v failed lookups", got)
+	}
+}
+
+func TestZoneSerial(t *testing.T) {
+	tests := []struct {
+		previous uint32
+		revision uint64
+		expected uint32
+	}{
+		{0, 0, 1},
+		{0, 4711, 4711},
+		{4711, 4800, 4800},
+		// objects were only removed
+		{4800, 4800, 4801},
+		{4800, 0, 4801},
+		// truncated to 32 bits, still greater in serial arithmetic
+		{0xfffffff0, 1<<32 + 5, 5},
+		{0xffffffff, 0, 1},
+	}
+	for i, tc := range tests {
+		if got := zoneSerial(tc.previous, tc.revision); got != tc.expected {
+			t.Errorf("Test %d: expected serial %d, got %d", i, tc.expected, got)
+		}
+	}
+
+	if !serialLess(0xfffffff0, 5) || serialLess(5, 0xfffffff0) || serialLess(5, 5) {
+		t.Errorf("expected serials to be compared with RFC 1982 arithmetic")
+	}
+
+	// replicas compute their records independently, only from the objects publishing
+	// them, whatever else they have seen change in the cluster
+	addrs := map[string][]netip.Addr{"svc1.ns1": {netip.MustParseAddr("192.0.2.1")}}
+	gw1 := newTransferGateway(addrs, map[string]uint64{"svc1.ns1": 4711})
+	gw2 := newTransferGateway(addrs, map[string]uint64{"svc1.ns1": 4711, "unrelated.ns1": 5000})
+	if s1, s2 := gw1.zoneVersion("example.com.").serial, gw2.zoneVersion("example.com.").serial; s1 != s2 || s1 != 4711 {
+		t.Errorf("expected replicas to agree on the serial 4711, got %d and %d", s1, s2)
+	}
+}